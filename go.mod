@@ -1,4 +1,8 @@
 module github.com/monopole/gorepomod
+
 go 1.15
 
-require golang.org/x/mod v0.3.0
+require (
+	golang.org/x/mod v0.6.0
+	gopkg.in/yaml.v2 v2.4.0
+)
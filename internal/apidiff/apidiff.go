@@ -0,0 +1,341 @@
+package apidiff
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+const (
+	goModFile = "go.mod"
+	goExt     = ".go"
+	testExt   = "_test.go"
+)
+
+// API maps each exported feature of a module (a function, a type,
+// a method, a struct field, a constant or a variable) to a string
+// describing its type.  Keys are qualified by the package's
+// directory relative to the module root, e.g. "yaml/merge2.Merge".
+type API map[string]string
+
+// FromDir extracts the exported API of the module rooted at dir,
+// ignoring any modules nested in it.
+func FromDir(dir string) (API, error) {
+	files := make(map[string][]byte)
+	err := filepath.Walk(
+		dir,
+		func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if p == dir {
+					return nil
+				}
+				if strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(p, goModFile)); err == nil {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(p, goExt) {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			content, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = content
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return FromFiles(files)
+}
+
+// FromFiles extracts the exported API from the given files,
+// keyed by slash-separated path relative to the module root.
+// Non-Go files, tests, commands (package main) and packages
+// below internal, testdata or vendor directories are ignored.
+func FromFiles(files map[string][]byte) (API, error) {
+	var names []string
+	for n := range files {
+		if isApiFile(n) {
+			names = append(names, n)
+		}
+	}
+	// Sort to get a deterministic result when files
+	// with different build constraints declare the same thing.
+	sort.Strings(names)
+	result := make(API)
+	fset := token.NewFileSet()
+	for _, n := range names {
+		f, err := parser.ParseFile(fset, n, files[n], 0)
+		if err != nil {
+			return nil, err
+		}
+		if f.Name.Name == "main" {
+			continue
+		}
+		dir := path.Dir(n)
+		if dir == "." {
+			dir = ""
+		}
+		result.addFile(dir, f)
+	}
+	return result, nil
+}
+
+func isApiFile(n string) bool {
+	if !strings.HasSuffix(n, goExt) || strings.HasSuffix(n, testExt) {
+		return false
+	}
+	for _, elem := range strings.Split(path.Dir(n), "/") {
+		switch elem {
+		case "internal", "testdata", "vendor":
+			return false
+		}
+		if strings.HasPrefix(elem, ".") && elem != "." {
+			return false
+		}
+	}
+	return true
+}
+
+func (a API) put(dir string, name string, desc string) {
+	if _, ok := a[qualify(dir, name)]; ok {
+		return
+	}
+	a[qualify(dir, name)] = desc
+}
+
+func qualify(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "." + name
+}
+
+func (a API) addFile(dir string, f *ast.File) {
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				a.put(dir, d.Name.Name, "func"+signature(d.Type))
+				continue
+			}
+			recv, ptr := receiver(d.Recv.List[0].Type)
+			if !ast.IsExported(recv) {
+				continue
+			}
+			a.put(
+				dir, recv+"."+d.Name.Name,
+				"method ("+ptr+recv+")"+signature(d.Type))
+		case *ast.GenDecl:
+			a.addGenDecl(dir, d)
+		}
+	}
+}
+
+func (a API) addGenDecl(dir string, d *ast.GenDecl) {
+	// In a const block, a spec lacking both type and values
+	// repeats the previous spec's type.
+	var implicit ast.Expr
+	for _, s := range d.Specs {
+		switch s := s.(type) {
+		case *ast.TypeSpec:
+			a.addType(dir, s)
+		case *ast.ValueSpec:
+			t := s.Type
+			if t != nil || len(s.Values) > 0 {
+				implicit = t
+			} else if d.Tok == token.CONST {
+				t = implicit
+			}
+			desc := d.Tok.String()
+			if t != nil {
+				desc += " " + types.ExprString(t)
+			}
+			for _, n := range s.Names {
+				if n.IsExported() {
+					a.put(dir, n.Name, desc)
+				}
+			}
+		}
+	}
+}
+
+func (a API) addType(dir string, s *ast.TypeSpec) {
+	if !s.Name.IsExported() {
+		return
+	}
+	st, ok := s.Type.(*ast.StructType)
+	if !ok {
+		desc := "type "
+		if s.Assign.IsValid() {
+			desc += "= "
+		}
+		a.put(dir, s.Name.Name, desc+types.ExprString(s.Type))
+		return
+	}
+	a.put(dir, s.Name.Name, "struct")
+	for _, f := range st.Fields.List {
+		desc := "field " + types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			if n, _ := receiver(f.Type); ast.IsExported(n) {
+				a.put(dir, s.Name.Name+"."+n, "embedded "+types.ExprString(f.Type))
+			}
+			continue
+		}
+		for _, n := range f.Names {
+			if n.IsExported() {
+				a.put(dir, s.Name.Name+"."+n.Name, desc)
+			}
+		}
+	}
+}
+
+// receiver returns the base type name of a method receiver
+// (or embedded field), and "*" if it's a pointer.
+func receiver(e ast.Expr) (name string, ptr string) {
+	if st, ok := e.(*ast.StarExpr); ok {
+		ptr = "*"
+		e = st.X
+	}
+	switch t := e.(type) {
+	case *ast.Ident:
+		return t.Name, ptr
+	case *ast.SelectorExpr:
+		return t.Sel.Name, ptr
+	case *ast.IndexExpr:
+		n, _ := receiver(t.X)
+		return n, ptr
+	}
+	return "", ptr
+}
+
+// signature renders parameter and result types, omitting names,
+// since renaming a parameter doesn't change an API.
+func signature(ft *ast.FuncType) string {
+	result := "(" + strings.Join(fieldTypes(ft.Params), ", ") + ")"
+	results := fieldTypes(ft.Results)
+	switch len(results) {
+	case 0:
+	case 1:
+		result += " " + results[0]
+	default:
+		result += " (" + strings.Join(results, ", ") + ")"
+	}
+	return result
+}
+
+func fieldTypes(fl *ast.FieldList) (result []string) {
+	if fl == nil {
+		return
+	}
+	for _, f := range fl.List {
+		t := types.ExprString(f.Type)
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			result = append(result, t)
+		}
+	}
+	return
+}
+
+// Report holds the differences between two APIs.
+type Report struct {
+	// Incompatible changes, i.e. removals and modifications,
+	// which might break code using the old API.
+	Incompatible []string
+	// Compatible changes, i.e. additions.
+	Compatible []string
+}
+
+// Compare reports how the API changed from old to new.
+func Compare(old, new API) *Report {
+	r := &Report{}
+	for k, v := range old {
+		nv, ok := new[k]
+		if !ok {
+			r.Incompatible = append(r.Incompatible, "removed "+k)
+			continue
+		}
+		if nv != v {
+			r.Incompatible = append(
+				r.Incompatible, fmt.Sprintf("changed %s: %s -> %s", k, v, nv))
+		}
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			r.Compatible = append(r.Compatible, "added "+k)
+		}
+	}
+	sort.Strings(r.Incompatible)
+	sort.Strings(r.Compatible)
+	return r
+}
+
+// Bump suggests how to bump the given version to release the new API.
+// Incompatible changes require a major bump, except in major version
+// zero, where anything goes, and a minor bump is used instead.
+// Compatible changes call for a minor bump (patch in version zero).
+func (r *Report) Bump(v semver.SemVer) semver.SvBump {
+	switch {
+	case len(r.Incompatible) > 0:
+		if v.Major() == 0 {
+			return semver.Minor
+		}
+		return semver.Major
+	case len(r.Compatible) > 0:
+		if v.Major() == 0 {
+			return semver.Patch
+		}
+		return semver.Minor
+	default:
+		return semver.Patch
+	}
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	if len(r.Incompatible) > 0 {
+		b.WriteString("incompatible changes:\n")
+		for _, s := range r.Incompatible {
+			b.WriteString("  " + s + "\n")
+		}
+	}
+	if len(r.Compatible) > 0 {
+		b.WriteString("compatible changes:\n")
+		for _, s := range r.Compatible {
+			b.WriteString("  " + s + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("no API changes\n")
+	}
+	return b.String()
+}
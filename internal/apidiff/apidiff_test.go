@@ -0,0 +1,150 @@
+package apidiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+func TestFromFiles(t *testing.T) {
+	files := map[string][]byte{
+		"k.go": []byte(`package kyaml
+
+type Kind int
+
+const (
+	Map Kind = iota
+	Seq
+	private
+)
+
+const Version = "1"
+
+var Default, other *Node
+
+type Node struct {
+	Name string
+	kids []*Node
+	Meta
+}
+
+type Meta struct{}
+
+type Filter interface {
+	Filter(*Node) (*Node, error)
+}
+
+func (n *Node) Walk(f func(n *Node), depth int) error { return nil }
+
+func (n node) Hidden() {}
+
+func Parse(a, b string, opts ...int) (*Node, error) { return nil, nil }
+
+func helper() {}
+`),
+		"yaml/merge2/m.go": []byte(`package merge2
+
+func Merge(a, b string) string { return a }
+`),
+		"yaml/merge2/m_test.go": []byte(`package merge2
+
+func TestOnly() {}
+`),
+		"internal/x/x.go": []byte(`package x
+
+func Internal() {}
+`),
+		"cmd/main.go": []byte(`package main
+
+func Main() {}
+`),
+		"README.md": []byte(`not go`),
+	}
+	expected := API{
+		"Kind":              "type int",
+		"Map":               "const Kind",
+		"Seq":               "const Kind",
+		"Version":           "const",
+		"Default":           "var *Node",
+		"Node":              "struct",
+		"Node.Name":         "field string",
+		"Node.Meta":         "embedded Meta",
+		"Node.Walk":         "method (*Node)(func(n *Node), int) error",
+		"Meta":              "struct",
+		"Filter":            "type interface{Filter(*Node) (*Node, error)}",
+		"Parse":             "func(string, string, ...int) (*Node, error)",
+		"yaml/merge2.Merge": "func(string, string) string",
+	}
+	actual, err := FromFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, actual)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	var testCases = map[string]struct {
+		old          API
+		new          API
+		v            semver.SemVer
+		incompatible []string
+		compatible   []string
+		bump         semver.SvBump
+	}{
+		"none": {
+			old:  API{"A": "func()"},
+			new:  API{"A": "func()"},
+			v:    semver.New(1, 2, 3),
+			bump: semver.Patch,
+		},
+		"added": {
+			old:        API{"A": "func()"},
+			new:        API{"A": "func()", "B": "func()"},
+			v:          semver.New(1, 2, 3),
+			compatible: []string{"added B"},
+			bump:       semver.Minor,
+		},
+		"added in v0": {
+			old:        API{},
+			new:        API{"B": "func()"},
+			v:          semver.New(0, 2, 3),
+			compatible: []string{"added B"},
+			bump:       semver.Patch,
+		},
+		"removed and changed": {
+			old: API{"A": "func()", "B": "func()", "C": "var int"},
+			new: API{"A": "func(int)", "C": "var int"},
+			v:   semver.New(1, 2, 3),
+			incompatible: []string{
+				"changed A: func() -> func(int)",
+				"removed B",
+			},
+			bump: semver.Major,
+		},
+		"removed in v0": {
+			old:          API{"A": "func()"},
+			new:          API{"B": "func()"},
+			v:            semver.New(0, 2, 3),
+			incompatible: []string{"removed A"},
+			compatible:   []string{"added B"},
+			bump:         semver.Minor,
+		},
+	}
+	for n, tc := range testCases {
+		r := Compare(tc.old, tc.new)
+		if !reflect.DeepEqual(tc.incompatible, r.Incompatible) {
+			t.Errorf("%s: expected incompatible %v, got %v",
+				n, tc.incompatible, r.Incompatible)
+		}
+		if !reflect.DeepEqual(tc.compatible, r.Compatible) {
+			t.Errorf("%s: expected compatible %v, got %v",
+				n, tc.compatible, r.Compatible)
+		}
+		if b := r.Bump(tc.v); b != tc.bump {
+			t.Errorf("%s: expected bump %v, got %v", n, tc.bump, b)
+		}
+	}
+}
@@ -3,29 +3,60 @@ package arguments
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/monopole/gorepomod/internal/config"
+	"github.com/monopole/gorepomod/internal/graph"
 	"github.com/monopole/gorepomod/internal/misc"
 	"github.com/monopole/gorepomod/internal/semver"
-	"github.com/monopole/gorepomod/internal/utils"
+	"github.com/monopole/gorepomod/listing"
 )
 
 const (
-	doItFlag     = "--doIt"
-	cmdPin       = "pin"
-	cmdUnPin     = "unpin"
-	cmdTidy      = "tidy"
-	cmdList      = "list"
-	cmdRelease   = "release"
-	cmdUnRelease = "unrelease"
-	cmdDebug = "debug"
+	doItFlag      = "--doIt"
+	flagPrefix    = "--"
+	outputFlag    = "output"
+	workspaceFlag = "workspace"
+	repoPathFlag  = "repoPath"
+	remoteFlag    = "remote"
+	mainBrFlag    = "mainBranch"
+	strategyFlag  = "strategy"
+	ontoFlag      = "onto"
+	skipPreFlag   = "skipPreflight"
+	signFlag      = "sign"
+	changelogFlag = "changelog"
+	nearestFlag   = "nearestTag"
+	cmdPin        = "pin"
+	cmdUnPin      = "unpin"
+	cmdTidy       = "tidy"
+	cmdList       = "list"
+	cmdRelease    = "release"
+	cmdUnRelease  = "unrelease"
+	cmdReleaseAll = "release-all"
+	cmdBackport   = "backport"
+	cmdResume     = "resume"
+	cmdAbort      = "abort"
+	cmdVerify     = "verify"
+	cmdMigrate    = "migrate-major"
+	cmdChanged    = "changed"
+	cmdSuggest    = "suggest-bump"
+	cmdCheckDeps  = "check-dependents"
+	cmdGraph      = "graph"
+	cmdWork       = "work"
+	workInit      = "init"
+	workSync      = "sync"
+	workCheck     = "check"
+	bumpAuto      = "auto"
+	cmdDebug      = "debug"
 )
 
 var (
 	commands = []string{
-		cmdPin, cmdUnPin, cmdTidy, cmdList, cmdRelease, cmdUnRelease, cmdDebug}
+		cmdPin, cmdUnPin, cmdTidy, cmdList, cmdRelease, cmdUnRelease,
+		cmdReleaseAll, cmdBackport, cmdResume, cmdAbort, cmdChanged, cmdSuggest,
+		cmdCheckDeps, cmdVerify, cmdMigrate, cmdGraph, cmdWork, cmdDebug}
 
-	// TODO: make this a PATH-like flag
-	// e.g.: --excludes ".git:.idea:site:docs"
+	// Exclusions always used, along with any in the config file.
 	excSlice = []string{
 		".git",
 		".github",
@@ -47,15 +78,60 @@ const (
 	List
 	Release
 	UnRelease
+	ReleaseAll
+	Backport
+	Resume
+	Abort
+	Verify
+	MigrateMajor
+	Changed
+	SuggestBump
+	CheckDependents
+	Graph
+	WorkInit
+	WorkSync
+	WorkCheck
 	Debug
 )
 
 type Args struct {
 	cmd        Command
 	moduleName misc.ModuleShortName
-	version    semver.SemVer
-	bump       semver.SvBump
-	doIt       bool
+	// More module names, for commands accepting many.
+	moduleNames []misc.ModuleShortName
+	version     semver.SemVer
+	bump        semver.SvBump
+	// The bump was given on the command line.
+	bumpSpecified bool
+	// Determine the bump from API changes.
+	autoBump bool
+	doIt     bool
+	output   listing.Format
+	graph    graph.Format
+	// Use a go.work file rather than go.mod replacements.
+	workspace bool
+	// The repository's import path, if specified.
+	repoPath string
+	// The git remote to use, if specified.
+	remote misc.TrackedRepo
+	// The name of the main branch, if specified.
+	mainBranch string
+	// Directories not searched for modules, besides the built-in ones.
+	exclusions []string
+	// The release strategy, if specified.
+	strategy misc.ReleaseStrategy
+	// Commits to backport.
+	commits []string
+	// The release line to backport to, e.g. v1.2.0 for v1.2.
+	onto semver.SemVer
+	// Don't run the preflight checks before releasing.
+	skipPreflight bool
+	// Sign release tags.
+	sign bool
+	// Add release notes to the module's changelog.
+	changelog bool
+	// Pin pseudo-version requirements to the nearest following tag.
+	nearestTag bool
 }
 
 func (a *Args) GetCommand() Command {
@@ -66,6 +142,16 @@ func (a *Args) Bump() semver.SvBump {
 	return a.bump
 }
 
+// BumpSpecified is true if the bump was specified,
+// rather than left to the configured default.
+func (a *Args) BumpSpecified() bool {
+	return a.bumpSpecified
+}
+
+func (a *Args) AutoBump() bool {
+	return a.autoBump
+}
+
 func (a *Args) Version() semver.SemVer {
 	return a.version
 }
@@ -74,21 +160,137 @@ func (a *Args) ModuleName() misc.ModuleShortName {
 	return a.moduleName
 }
 
+func (a *Args) ModuleNames() []misc.ModuleShortName {
+	return a.moduleNames
+}
+
+// Commits are the commits to backport.
+func (a *Args) Commits() []string {
+	return a.commits
+}
+
+// Onto is the release line to backport to,
+// e.g. v1.2.0 for the line v1.2.
+func (a *Args) Onto() semver.SemVer {
+	return a.onto
+}
+
+// Exclusions are the built-in exclusions,
+// followed by any from the configuration.
 func (a *Args) Exclusions() (result []string) {
 	// Make sure the list has no repeats.
-	for k := range utils.SliceToSet(excSlice) {
-		result = append(result, k)
+	seen := make(map[string]bool)
+	for _, l := range [][]string{excSlice, a.exclusions} {
+		for _, e := range l {
+			if !seen[e] {
+				seen[e] = true
+				result = append(result, e)
+			}
+		}
 	}
 	return
 }
 
+// ApplyConfig uses the repository's configuration
+// for whatever wasn't specified on the command line.
+func (a *Args) ApplyConfig(c *config.Config) {
+	if a.remote == "" {
+		a.remote = misc.TrackedRepo(c.Remote)
+	}
+	if a.mainBranch == "" {
+		a.mainBranch = c.MainBranch
+	}
+	if len(c.Excludes) > 0 {
+		a.exclusions = c.Excludes
+	}
+	if a.strategy == "" {
+		// Validated on load.
+		a.strategy = misc.ReleaseStrategy(c.Strategy)
+	}
+}
+
+// SkipPreflight is true if the checks made before
+// a release should be skipped.
+func (a *Args) SkipPreflight() bool {
+	return a.skipPreflight
+}
+
+// Sign is true if release tags should be signed,
+// even if the configuration doesn't say so.
+func (a *Args) Sign() bool {
+	return a.sign
+}
+
+// Changelog is true if release should update the module's
+// changelog, even if the configuration doesn't say so.
+func (a *Args) Changelog() bool {
+	return a.changelog
+}
+
 func (a *Args) DoIt() bool {
 	return a.doIt
 }
 
+func (a *Args) Output() listing.Format {
+	return a.output
+}
+
+// RepoPath is the repository's import path,
+// or empty if it should be inferred.
+func (a *Args) RepoPath() string {
+	return a.repoPath
+}
+
+// Remote is the git remote to use,
+// or empty if it should be determined.
+func (a *Args) Remote() misc.TrackedRepo {
+	return a.remote
+}
+
+// MainBranch is the name of the main branch,
+// or empty if it should be determined.
+func (a *Args) MainBranch() string {
+	return a.mainBranch
+}
+
+// Strategy is the release strategy.
+func (a *Args) Strategy() misc.ReleaseStrategy {
+	if a.strategy == "" {
+		return misc.BranchPerMinor
+	}
+	return a.strategy
+}
+
+func (a *Args) Workspace() bool {
+	return a.workspace
+}
+
+// NearestTag is true if requirements on untagged commits
+// should be pinned to the nearest following tag.
+func (a *Args) NearestTag() bool {
+	return a.nearestTag
+}
+
+func (a *Args) GraphFormat() graph.Format {
+	return a.graph
+}
+
 type myArgs struct {
 	args []string
 	doIt bool
+	// Flags of the form --name=value, by name.
+	flags map[string]string
+}
+
+// flag consumes and returns the value of the named flag,
+// or returns the default if the flag wasn't specified.
+func (a *myArgs) flag(name string, dflt string) string {
+	v, ok := a.flags[name]
+	if !ok {
+		return dflt
+	}
+	delete(a.flags, name)
+	return v
 }
 
 func (a *myArgs) next() (result string) {
@@ -100,16 +302,37 @@ func (a *myArgs) next() (result string) {
 	return
 }
 
+func (a *myArgs) peek() string {
+	if !a.more() {
+		panic("no args left")
+	}
+	return a.args[0]
+}
+
 func (a *myArgs) more() bool {
 	return len(a.args) > 0
 }
 
+// boolFlag consumes the named flag, returning true if
+// it was specified without a value, or with value "true".
+func (a *myArgs) boolFlag(name string) bool {
+	v := a.flag(name, "false")
+	return v == "" || v == "true"
+}
+
 func newArgs() *myArgs {
-	result := &myArgs{}
+	result := &myArgs{flags: make(map[string]string)}
 	for _, a := range os.Args[1:] {
-		if a == doItFlag {
+		switch {
+		case a == doItFlag:
 			result.doIt = true
-		} else {
+		case strings.HasPrefix(a, flagPrefix):
+			nv := strings.SplitN(a[len(flagPrefix):], "=", 2)
+			if len(nv) == 1 {
+				nv = append(nv, "")
+			}
+			result.flags[nv[0]] = nv[1]
+		default:
 			result.args = append(result.args, a)
 		}
 	}
@@ -120,12 +343,21 @@ func Parse() (result *Args, err error) {
 	result = &Args{}
 	clArgs := newArgs()
 	result.doIt = clArgs.doIt
+	result.repoPath = clArgs.flag(repoPathFlag, "")
+	result.remote = misc.TrackedRepo(clArgs.flag(remoteFlag, ""))
+	result.mainBranch = clArgs.flag(mainBrFlag, "")
+	if s := clArgs.flag(strategyFlag, ""); s != "" {
+		result.strategy, err = misc.ParseReleaseStrategy(s)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	result.moduleName = misc.ModuleUnknown
 	if !clArgs.more() {
 		return nil, fmt.Errorf("command needs at least one arg")
 	}
-	command:= clArgs.next()
+	command := clArgs.next()
 	switch command {
 	case cmdPin:
 		if !clArgs.more() {
@@ -140,38 +372,159 @@ func Parse() (result *Args, err error) {
 		} else {
 			result.version = semver.Zero()
 		}
+		result.nearestTag = clArgs.boolFlag(nearestFlag)
+		if result.nearestTag && !result.version.IsZero() {
+			return nil, fmt.Errorf(
+				"pin takes either a version or --%s, not both", nearestFlag)
+		}
 		result.cmd = Pin
 	case cmdUnPin:
 		if !clArgs.more() {
 			return nil, fmt.Errorf("unpin needs a moduleName to unpin")
 		}
 		result.moduleName = misc.ModuleShortName(clArgs.next())
+		result.workspace = clArgs.boolFlag(workspaceFlag)
 		result.cmd = UnPin
 	case cmdTidy:
 		result.cmd = Tidy
 	case cmdList:
+		result.output, err = listing.ParseFormat(
+			clArgs.flag(outputFlag, string(listing.Table)))
+		if err != nil {
+			return nil, err
+		}
 		result.cmd = List
+	case cmdChanged:
+		result.cmd = Changed
+	case cmdWork:
+		if !clArgs.more() {
+			return nil, fmt.Errorf(
+				"specify %q, %q or %q", workInit, workSync, workCheck)
+		}
+		switch op := clArgs.next(); op {
+		case workInit:
+			result.cmd = WorkInit
+		case workSync:
+			result.cmd = WorkSync
+		case workCheck:
+			result.cmd = WorkCheck
+		default:
+			return nil, fmt.Errorf(
+				"unknown work operation %q; specify %q, %q or %q",
+				op, workInit, workSync, workCheck)
+		}
+	case cmdGraph:
+		result.graph = graph.Dot
+		if clArgs.more() {
+			result.graph, err = graph.ParseFormat(clArgs.next())
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.cmd = Graph
+	case cmdSuggest:
+		if !clArgs.more() {
+			return nil, fmt.Errorf("specify {module} to examine")
+		}
+		result.moduleName = misc.ModuleShortName(clArgs.next())
+		result.cmd = SuggestBump
+	case cmdCheckDeps:
+		if !clArgs.more() {
+			return nil, fmt.Errorf("specify {module} whose dependents to check")
+		}
+		result.moduleName = misc.ModuleShortName(clArgs.next())
+		result.cmd = CheckDependents
 	case cmdRelease:
 		if !clArgs.more() {
 			return nil, fmt.Errorf("specify {module} to release")
 		}
 		result.moduleName = misc.ModuleShortName(clArgs.next())
-		bump := "patch"
 		if clArgs.more() {
-			bump = clArgs.next()
-		}
-		switch bump {
-		case "major":
-			result.bump = semver.Major
-		case "minor":
-			result.bump = semver.Minor
-		case "patch":
-			result.bump = semver.Patch
-		default:
-			return nil, fmt.Errorf(
-				"unknown bump %s; specify one of 'major', 'minor' or 'patch'", bump)
+			result.bumpSpecified = true
+			bump := clArgs.next()
+			if bump == bumpAuto {
+				result.autoBump = true
+			} else {
+				result.bump, err = semver.ParseBump(bump)
+				if err != nil {
+					return nil, fmt.Errorf("%v, or %q", err, bumpAuto)
+				}
+			}
 		}
+		result.skipPreflight = clArgs.boolFlag(skipPreFlag)
+		result.sign = clArgs.boolFlag(signFlag)
+		result.changelog = clArgs.boolFlag(changelogFlag)
 		result.cmd = Release
+	case cmdReleaseAll:
+		if clArgs.more() {
+			if b, err := semver.ParseBump(clArgs.peek()); err == nil {
+				clArgs.next()
+				result.bump = b
+				result.bumpSpecified = true
+			}
+		}
+		for clArgs.more() {
+			result.moduleNames = append(
+				result.moduleNames, misc.ModuleShortName(clArgs.next()))
+		}
+		result.skipPreflight = clArgs.boolFlag(skipPreFlag)
+		result.sign = clArgs.boolFlag(signFlag)
+		result.changelog = clArgs.boolFlag(changelogFlag)
+		result.cmd = ReleaseAll
+	case cmdBackport:
+		if !clArgs.more() {
+			return nil, fmt.Errorf("specify {module} to backport to")
+		}
+		result.moduleName = misc.ModuleShortName(clArgs.next())
+		for clArgs.more() {
+			result.commits = append(result.commits, clArgs.next())
+		}
+		line := clArgs.flag(ontoFlag, "")
+		if line == "" && len(result.commits) > 0 {
+			// Allow "--onto v1.2" as well as "--onto=v1.2".
+			last := result.commits[len(result.commits)-1]
+			if _, err := semver.ParseLine(last); err == nil {
+				line = last
+				result.commits = result.commits[:len(result.commits)-1]
+			}
+		}
+		if line == "" {
+			return nil, fmt.Errorf(
+				"specify the release line with --%s, e.g. --%s=v1.2",
+				ontoFlag, ontoFlag)
+		}
+		result.onto, err = semver.ParseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.commits) == 0 {
+			return nil, fmt.Errorf("specify {commit...} to backport")
+		}
+		result.sign = clArgs.boolFlag(signFlag)
+		result.cmd = Backport
+	case cmdResume:
+		result.cmd = Resume
+	case cmdAbort:
+		result.cmd = Abort
+	case cmdVerify:
+		if !clArgs.more() {
+			return nil, fmt.Errorf("specify {module} whose tags to verify")
+		}
+		result.moduleName = misc.ModuleShortName(clArgs.next())
+		result.version = semver.Zero()
+		if clArgs.more() {
+			result.version, err = semver.Parse(clArgs.next())
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.cmd = Verify
+	case cmdMigrate:
+		if !clArgs.more() {
+			return nil, fmt.Errorf("specify {module} to move to a new major version")
+		}
+		result.moduleName = misc.ModuleShortName(clArgs.next())
+		result.cmd = MigrateMajor
 	case cmdUnRelease:
 		if !clArgs.more() {
 			return nil, fmt.Errorf("specify {module} to unrelease")
@@ -191,5 +544,9 @@ func Parse() (result *Args, err error) {
 	if clArgs.more() {
 		return nil, fmt.Errorf("unknown extra args: %v", clArgs.args)
 	}
+	if len(clArgs.flags) > 0 {
+		return nil, fmt.Errorf(
+			"unknown flags for %s: %v", command, clArgs.flags)
+	}
 	return
 }
@@ -0,0 +1,20 @@
+package arguments
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/config"
+)
+
+func TestExclusions(t *testing.T) {
+	a := &Args{}
+	if actual := a.Exclusions(); !reflect.DeepEqual(actual, excSlice) {
+		t.Errorf("expected %v, got %v", excSlice, actual)
+	}
+	a.ApplyConfig(&config.Config{Excludes: []string{"testdata", "docs"}})
+	expected := append(append([]string(nil), excSlice...), "testdata")
+	if actual := a.Exclusions(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
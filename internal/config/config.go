@@ -0,0 +1,286 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+	"github.com/monopole/gorepomod/internal/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// FileName is the name of the configuration file,
+// found in the repository root.
+const FileName = ".gorepomod.yaml"
+
+// Config holds repository-wide settings, so that
+// every contributor runs gorepomod the same way.
+// Command line flags override these settings.
+type Config struct {
+	// Excludes are patterns naming directories that are not
+	// searched for modules.  Patterns use path.Match syntax.
+	// A pattern without a slash is matched against a directory's
+	// base name, e.g. "testdata".  A pattern with a slash is matched
+	// against the directory's slash separated path relative to the
+	// repository root, e.g. "plugin/*/internal".
+	// These are added to the built-in exclusions, e.g. ".git".
+	Excludes []string `yaml:"excludes,omitempty"`
+
+	// Remote is the git remote to use, e.g. "upstream".
+	Remote string `yaml:"remote,omitempty"`
+
+	// MainBranch is the branch releases are made from, e.g. "main".
+	MainBranch string `yaml:"mainBranch,omitempty"`
+
+	// Strategy is the release strategy, e.g. "trunk".
+	// Defaults to "branch-per-minor".
+	Strategy string `yaml:"strategy,omitempty"`
+
+	// BranchTemplate is a text/template naming release branches.
+	// See naming.Data for the fields available, and
+	// naming.DefaultBranch for the default.
+	BranchTemplate string `yaml:"branchTemplate,omitempty"`
+
+	// TagTemplate is a text/template naming release tags.
+	// A template yielding tags other than those the Go
+	// toolchain looks for is rejected.
+	// See naming.DefaultTag for the default.
+	TagTemplate string `yaml:"tagTemplate,omitempty"`
+
+	// DefaultBump is the bump used by release and release-all
+	// when none is specified, e.g. "minor".  Defaults to "patch".
+	DefaultBump string `yaml:"defaultBump,omitempty"`
+
+	// Preflight configures the checks made before a release.
+	Preflight Preflight `yaml:"preflight,omitempty"`
+
+	// Signing says how release tags are signed.
+	Signing Signing `yaml:"signing,omitempty"`
+
+	// Changelog, if true, has release add the release notes to
+	// a CHANGELOG.md file in the module's directory, committed
+	// to the main branch and merged into the release branch.
+	Changelog bool `yaml:"changelog,omitempty"`
+
+	// Modules holds per-module settings, keyed by module short
+	// name as shown by 'gorepomod list', e.g. "kyaml".
+	Modules map[string]Module `yaml:"modules,omitempty"`
+}
+
+// DefaultPreflightCommands are run in a module before releasing it.
+var DefaultPreflightCommands = []string{
+	"go build ./...",
+	"go vet ./...",
+	"go test ./...",
+}
+
+// Preflight configures the checks made before a release.
+type Preflight struct {
+	// Skip turns the checks off.
+	Skip bool `yaml:"skip,omitempty"`
+	// Commands are run, in order, in the module to be released,
+	// split into arguments as a shell would (quotes and
+	// backslashes work), but run without one.
+	// Defaults to DefaultPreflightCommands.
+	Commands []string `yaml:"commands,omitempty"`
+	// Dependents, if true, runs the commands in each in-repo
+	// module depending on the module to be released, building
+	// against the module's local (to be released) code.
+	Dependents bool `yaml:"dependents,omitempty"`
+}
+
+// PreflightCommands returns the commands to run before a release,
+// each split into its arguments.
+func (c *Config) PreflightCommands() (result [][]string) {
+	commands := c.Preflight.Commands
+	if len(commands) == 0 {
+		commands = DefaultPreflightCommands
+	}
+	for _, cmd := range commands {
+		// Configured commands were checked when loaded.
+		args, _ := splitCommand(cmd)
+		result = append(result, args)
+	}
+	return
+}
+
+// splitCommand splits the command into its arguments as a POSIX
+// shell would, minus expansions: arguments are separated by white
+// space, unless quoted with ' or ", or escaped with a backslash,
+// e.g. go test -run "TestA|TestB" ./...
+func splitCommand(cmd string) (result []string, err error) {
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				result = append(result, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 == len(cmd) {
+				return nil, fmt.Errorf("trailing backslash in %q", cmd)
+			}
+			i++
+			word.WriteByte(cmd[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ' in %q", cmd)
+			}
+			word.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(cmd) && cmd[i] != '"'; i++ {
+				// Within double quotes, a backslash escapes only these.
+				if cmd[i] == '\\' && i+1 < len(cmd) &&
+					strings.IndexByte("\"\\$`", cmd[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(cmd[i])
+			}
+			if i == len(cmd) {
+				return nil, fmt.Errorf("unterminated \" in %q", cmd)
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		result = append(result, word.String())
+	}
+	return result, nil
+}
+
+// SigningFormats are the recognized signature formats,
+// as named by git's gpg.format setting, save "gpg" for "openpgp".
+var SigningFormats = []string{"gpg", "ssh", "x509"}
+
+// Signing says how release tags are signed.
+type Signing struct {
+	// Format is one of SigningFormats.  If empty, tags aren't
+	// signed, unless a key is given, in which case git's own
+	// gpg.format setting holds (by default, GPG).
+	Format string `yaml:"format,omitempty"`
+	// Key is the signing key, e.g. a GPG key ID, or for "ssh",
+	// the path to a key.  Defaults to git's user.signingKey.
+	Key string `yaml:"key,omitempty"`
+}
+
+// Module holds settings for one module.
+type Module struct {
+	// NeverRelease marks a module that must not be released,
+	// e.g. one holding only tools or tests.
+	NeverRelease bool `yaml:"neverRelease,omitempty"`
+
+	// DefaultBump overrides Config.DefaultBump for the module.
+	DefaultBump string `yaml:"defaultBump,omitempty"`
+}
+
+// Load reads the configuration file in the given directory.
+// If there's no such file, the result is an empty Config.
+func Load(dir string) (*Config, error) {
+	p := filepath.Join(dir, FileName)
+	c := &Config{}
+	if !utils.PathExists(p) {
+		return c, nil
+	}
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, fmt.Errorf("in %s: %v", p, err)
+	}
+	if err = c.validate(); err != nil {
+		return nil, fmt.Errorf("in %s: %v", p, err)
+	}
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	for _, pat := range c.Excludes {
+		if _, err := path.Match(pat, ""); err != nil {
+			return fmt.Errorf("bad exclude pattern %q", pat)
+		}
+	}
+	for _, cmd := range c.Preflight.Commands {
+		args, err := splitCommand(cmd)
+		if err != nil {
+			return fmt.Errorf("bad preflight command; %v", err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("empty preflight command")
+		}
+	}
+	if f := c.Signing.Format; f != "" && !utils.SliceToSet(SigningFormats)[f] {
+		return fmt.Errorf(
+			"unknown signing format %q; must be one of %v", f, SigningFormats)
+	}
+	if c.Strategy != "" {
+		if _, err := misc.ParseReleaseStrategy(c.Strategy); err != nil {
+			return err
+		}
+	}
+	if c.DefaultBump != "" {
+		if _, err := semver.ParseBump(c.DefaultBump); err != nil {
+			return err
+		}
+	}
+	for n, m := range c.Modules {
+		if m.DefaultBump != "" {
+			if _, err := semver.ParseBump(m.DefaultBump); err != nil {
+				return fmt.Errorf("module %s: %v", n, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Bump returns the default bump for the module.
+func (c *Config) Bump(n misc.ModuleShortName) semver.SvBump {
+	raw := c.Modules[string(n)].DefaultBump
+	if raw == "" {
+		raw = c.DefaultBump
+	}
+	if raw == "" {
+		return semver.Patch
+	}
+	// Validated on load.
+	b, _ := semver.ParseBump(raw)
+	return b
+}
+
+// NeverRelease is true if the module must not be released.
+func (c *Config) NeverRelease(n misc.ModuleShortName) bool {
+	return c.Modules[string(n)].NeverRelease
+}
+
+// IsExcluded is true if the directory, given by its slash separated
+// path relative to the repository root, matches any of the patterns.
+// See Config.Excludes.
+func IsExcluded(patterns []string, rel string) bool {
+	base := path.Base(rel)
+	for _, pat := range patterns {
+		target := base
+		if strings.Contains(pat, "/") {
+			target = rel
+		}
+		if ok, _ := path.Match(pat, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+func TestIsExcluded(t *testing.T) {
+	patterns := []string{"docs", "test*", "plugin/*/internal"}
+	var testCases = map[string]struct {
+		rel      string
+		excluded bool
+	}{
+		"baseAtTop": {
+			rel:      "docs",
+			excluded: true,
+		},
+		"baseNested": {
+			rel:      "api/docs",
+			excluded: true,
+		},
+		"baseGlob": {
+			rel:      "api/testdata",
+			excluded: true,
+		},
+		"pathGlob": {
+			rel:      "plugin/foo/internal",
+			excluded: true,
+		},
+		"pathGlobWrongDepth": {
+			rel:      "x/plugin/foo/internal",
+			excluded: false,
+		},
+		"notExcluded": {
+			rel:      "api/internal",
+			excluded: false,
+		},
+	}
+	for n, tc := range testCases {
+		if actual := IsExcluded(patterns, tc.rel); actual != tc.excluded {
+			t.Errorf("%s: expected %v, got %v", n, tc.excluded, actual)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	var testCases = map[string]struct {
+		content string
+		errMsg  string
+		remote  string
+		bumps   map[misc.ModuleShortName]semver.SvBump
+		never   misc.ModuleShortName
+	}{
+		"noFile": {
+			bumps: map[misc.ModuleShortName]semver.SvBump{
+				"kyaml": semver.Patch,
+			},
+		},
+		"full": {
+			content: `
+excludes:
+- docs
+- "plugin/*/internal"
+remote: github
+mainBranch: main
+defaultBump: minor
+modules:
+  kyaml:
+    defaultBump: major
+  hack:
+    neverRelease: true
+`,
+			remote: "github",
+			bumps: map[misc.ModuleShortName]semver.SvBump{
+				"kyaml": semver.Major,
+				"api":   semver.Minor,
+			},
+			never: "hack",
+		},
+		"badBump": {
+			content: "defaultBump: huge\n",
+			errMsg:  "unknown bump huge",
+		},
+		"badPattern": {
+			content: "excludes: [\"[\"]\n",
+			errMsg:  "bad exclude pattern",
+		},
+		"emptyPreflightCommand": {
+			content: "preflight:\n  commands: [\"go vet ./...\", \" \"]\n",
+			errMsg:  "empty preflight command",
+		},
+		"unterminatedQuote": {
+			content: "preflight:\n  commands: ['go test -run \"TestA ./...']\n",
+			errMsg:  "bad preflight command; unterminated \"",
+		},
+		"badSigningFormat": {
+			content: "signing:\n  format: pgp\n",
+			errMsg:  "unknown signing format \"pgp\"",
+		},
+		"unknownField": {
+			content: "remotes: github\n",
+			errMsg:  "field remotes not found",
+		},
+	}
+	for n, tc := range testCases {
+		dir, err := ioutil.TempDir("", "config")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		if tc.content != "" {
+			err = ioutil.WriteFile(
+				filepath.Join(dir, FileName), []byte(tc.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		c, err := Load(dir)
+		if tc.errMsg != "" {
+			if err == nil {
+				t.Errorf("%s: expected error containing %q", n, tc.errMsg)
+			} else if !strings.Contains(err.Error(), tc.errMsg) {
+				t.Errorf("%s: expected error containing %q, got %v",
+					n, tc.errMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", n, err)
+			continue
+		}
+		if c.Remote != tc.remote {
+			t.Errorf("%s: expected remote %q, got %q", n, tc.remote, c.Remote)
+		}
+		for m, b := range tc.bumps {
+			if actual := c.Bump(m); actual != b {
+				t.Errorf("%s: expected %s bump %s, got %s", n, m, b, actual)
+			}
+			if c.NeverRelease(m) {
+				t.Errorf("%s: didn't expect %s to be never released", n, m)
+			}
+		}
+		if tc.never != "" && !c.NeverRelease(tc.never) {
+			t.Errorf("%s: expected %s to be never released", n, tc.never)
+		}
+	}
+}
+
+func TestPreflightCommands(t *testing.T) {
+	var testCases = map[string]struct {
+		commands []string
+		expected [][]string
+	}{
+		"default": {
+			expected: [][]string{
+				{"go", "build", "./..."},
+				{"go", "vet", "./..."},
+				{"go", "test", "./..."},
+			},
+		},
+		"configured": {
+			commands: []string{"make  lint", "go test -short ./..."},
+			expected: [][]string{
+				{"make", "lint"},
+				{"go", "test", "-short", "./..."},
+			},
+		},
+		"quoted": {
+			commands: []string{`go test -run "TestA|TestB" ./...`},
+			expected: [][]string{
+				{"go", "test", "-run", "TestA|TestB", "./..."},
+			},
+		},
+	}
+	for n, tc := range testCases {
+		c := &Config{Preflight: Preflight{Commands: tc.commands}}
+		if actual := c.PreflightCommands(); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", n, tc.expected, actual)
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	var testCases = map[string]struct {
+		cmd      string
+		expected []string
+		errMsg   string
+	}{
+		"plain": {
+			cmd:      " go  vet\t./... ",
+			expected: []string{"go", "vet", "./..."},
+		},
+		"doubleQuoted": {
+			cmd:      `go test -run "TestA|TestB" ./...`,
+			expected: []string{"go", "test", "-run", "TestA|TestB", "./..."},
+		},
+		"singleQuoted": {
+			cmd:      `sh -c 'go vet ./... && echo "ok"'`,
+			expected: []string{"sh", "-c", `go vet ./... && echo "ok"`},
+		},
+		"escapesInDoubleQuotes": {
+			cmd:      `echo "a \"b\" \c"`,
+			expected: []string{"echo", `a "b" \c`},
+		},
+		"backslash": {
+			cmd:      `echo a\ b`,
+			expected: []string{"echo", "a b"},
+		},
+		"adjacentQuotes": {
+			cmd:      `echo -X'a b'"c" ""`,
+			expected: []string{"echo", "-Xa bc", ""},
+		},
+		"empty": {
+			cmd: "  ",
+		},
+		"unterminatedSingle": {
+			cmd:    "echo 'a",
+			errMsg: "unterminated '",
+		},
+		"unterminatedDouble": {
+			cmd:    `echo "a\"`,
+			errMsg: `unterminated "`,
+		},
+		"trailingBackslash": {
+			cmd:    `echo a\`,
+			errMsg: "trailing backslash",
+		},
+	}
+	for n, tc := range testCases {
+		actual, err := splitCommand(tc.cmd)
+		if tc.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Errorf("%s: expected error containing %q, got %v",
+					n, tc.errMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", n, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %q, got %q", n, tc.expected, actual)
+		}
+	}
+}
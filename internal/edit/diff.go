@@ -0,0 +1,134 @@
+package edit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lines of unchanged context shown around changes.
+const diffContext = 3
+
+// diffOp is one line of an edit script turning a into b.
+type diffOp struct {
+	// ' ' (keep), '-' (delete from a) or '+' (insert from b).
+	kind byte
+	line string
+	// Indices into a and b at the time of the op.
+	ai, bi int
+}
+
+// unifiedDiff returns a unified diff turning content a into b,
+// naming the file as in git, or "" if there's no difference.
+func unifiedDiff(name string, a, b []byte) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+	var changes []int
+	for i, o := range ops {
+		if o.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("--- a/" + name + "\n")
+	sb.WriteString("+++ b/" + name + "\n")
+	for k := 0; k < len(changes); {
+		// Grow the hunk while the next change is close enough
+		// that the contexts would touch.
+		last := k
+		for last+1 < len(changes) &&
+			changes[last+1]-changes[last] <= 2*diffContext {
+			last++
+		}
+		start := max(0, changes[k]-diffContext)
+		end := min(len(ops), changes[last]+diffContext+1)
+		writeHunk(&sb, ops[start:end])
+		k = last + 1
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp) {
+	aCount, bCount := 0, 0
+	for _, o := range ops {
+		if o.kind != '+' {
+			aCount++
+		}
+		if o.kind != '-' {
+			bCount++
+		}
+	}
+	// Line numbers are one-based; an empty range
+	// names the line before it.
+	aStart, bStart := ops[0].ai+1, ops[0].bi+1
+	if aCount == 0 {
+		aStart--
+	}
+	if bCount == 0 {
+		bStart--
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, o := range ops {
+		sb.WriteByte(o.kind)
+		sb.WriteString(o.line)
+		sb.WriteByte('\n')
+	}
+}
+
+func splitLines(content []byte) []string {
+	s := strings.TrimSuffix(string(content), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines computes a minimal edit script via the
+// longest common subsequence of the lines.
+func diffLines(a, b []string) (ops []diffOp) {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], ai: i, bi: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], ai: i, bi: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], ai: i, bi: j})
+			j++
+		}
+	}
+	return
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
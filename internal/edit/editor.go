@@ -2,15 +2,21 @@ package edit
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/monopole/gorepomod/internal/misc"
 	"github.com/monopole/gorepomod/internal/semver"
+	"golang.org/x/mod/modfile"
 )
 
-// Editor runs `go mod` commands on an instance of Module.
-// If doIt is false, the command is printed, but not run.
+const goModFile = "go.mod"
+
+// Editor changes the go.mod file of an instance of Module,
+// and runs `go mod` commands on it.
+// If doIt is false, changes are printed (as a diff), but not made.
 type Editor struct {
 	module misc.LaModule
 	doIt   bool
@@ -27,18 +33,54 @@ func (e *Editor) run(args ...string) error {
 	c := exec.Command(
 		"go",
 		append([]string{"mod"}, args...)...)
-	c.Dir = string(e.module.ShortName())
+	c.Dir = e.module.AbsPath()
 	if e.doIt {
 		out, err := c.CombinedOutput()
 		if err != nil {
 			return fmt.Errorf("%s out=%q", err.Error(), out)
 		}
 	} else {
-		fmt.Printf("in %-60s; %s\n", c.Dir, c.String())
+		fmt.Printf("in %-60s; %s\n", e.module.ShortName(), c.String())
 	}
 	return nil
 }
 
+// edit applies the function to the module's go.mod file,
+// and writes the result back, or, if doIt is false,
+// prints the change as a unified diff.
+func (e *Editor) edit(f func(mf *modfile.File) error) error {
+	p := filepath.Join(e.module.AbsPath(), goModFile)
+	before, err := ioutil.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	mf, err := modfile.Parse(p, before, nil)
+	if err != nil {
+		return err
+	}
+	if err = f(mf); err != nil {
+		return err
+	}
+	mf.Cleanup()
+	after, err := mf.Format()
+	if err != nil {
+		return err
+	}
+	if !e.doIt {
+		fmt.Print(unifiedDiff(e.relPath(), before, after))
+		return nil
+	}
+	return ioutil.WriteFile(p, after, 0644)
+}
+
+// relPath is the go.mod file's path relative to the repository root.
+func (e *Editor) relPath() string {
+	if e.module.ShortName() == misc.ModuleAtTop {
+		return goModFile
+	}
+	return string(e.module.ShortName()) + "/" + goModFile
+}
+
 func upstairs(depth int) string {
 	var b strings.Builder
 	for i := 0; i < depth; i++ {
@@ -47,36 +89,122 @@ func upstairs(depth int) string {
 	return b.String()
 }
 
+// localPath is the relative path from the editor's module
+// to the target module, in the form used by replacements.
+func (e *Editor) localPath(target misc.LaModule) string {
+	up := upstairs(e.module.ShortName().Depth())
+	if target.ShortName() == misc.ModuleAtTop {
+		return strings.TrimSuffix(up, "/")
+	}
+	if up == "" {
+		up = "./"
+	}
+	return up + string(target.ShortName())
+}
+
 func (e *Editor) Tidy() error {
 	return e.run("tidy")
 }
 
-func (e *Editor) Pin(target misc.LaModule, oldV, newV semver.SemVer) error {
-	err := e.run(
-		"edit",
-		"-dropreplace="+target.ImportPath()+"@"+oldV.String(),
-		"-require="+target.ImportPath()+"@"+newV.String(),
-	)
+// Pin drops any replacement of the target module,
+// and requires the target at the given version.
+func (e *Editor) Pin(target misc.LaModule, newV semver.SemVer) error {
+	err := e.edit(func(mf *modfile.File) error {
+		return pin(mf, target.ImportPath(), newV)
+	})
 	if err != nil {
 		return err
 	}
 	return e.run("tidy")
 }
 
+func pin(mf *modfile.File, path string, v semver.SemVer) error {
+	var old []modfile.Replace
+	for _, r := range mf.Replace {
+		if r.Old.Path == path {
+			old = append(old, *r)
+		}
+	}
+	for _, r := range old {
+		if err := mf.DropReplace(r.Old.Path, r.Old.Version); err != nil {
+			return err
+		}
+	}
+	return mf.AddRequire(path, v.String())
+}
+
+// UnPin replaces the target module, at the given version,
+// with a relative path to the target's in-repo directory.
 func (e *Editor) UnPin(target misc.LaModule, oldV semver.SemVer) error {
-	var r strings.Builder
-	r.WriteString(target.ImportPath())
-	r.WriteString("@")
-	r.WriteString(oldV.String())
-	r.WriteString("=")
-	r.WriteString(upstairs(e.module.ShortName().Depth()))
-	r.WriteString(string(target.ShortName()))
-	err := e.run(
-		"edit",
-		"-replace="+r.String(),
-	)
+	err := e.edit(func(mf *modfile.File) error {
+		return mf.AddReplace(
+			target.ImportPath(), oldV.String(), e.localPath(target), "")
+	})
 	if err != nil {
 		return err
 	}
 	return e.run("tidy")
 }
+
+// SetModulePath changes the path in the module directive,
+// e.g. when moving to a new major version.
+func (e *Editor) SetModulePath(path string) error {
+	return e.edit(func(mf *modfile.File) error {
+		return mf.AddModuleStmt(path)
+	})
+}
+
+// MoveDependency changes the requirement of the target module to a
+// requirement of the given path at the given version, e.g. when the
+// target moves to a new major version.  Replacements of the target
+// are moved likewise; if there are none, the new path is replaced
+// by the target's in-repo directory, as the version isn't released.
+func (e *Editor) MoveDependency(
+	target misc.LaModule, newPath string, v semver.SemVer) error {
+	return e.edit(func(mf *modfile.File) error {
+		return moveDependency(
+			mf, target.ImportPath(), newPath, v, e.localPath(target))
+	})
+}
+
+func moveDependency(
+	mf *modfile.File, oldPath, newPath string,
+	v semver.SemVer, localPath string) error {
+	required := false
+	for _, r := range mf.Require {
+		if r.Mod.Path == oldPath {
+			required = true
+		}
+	}
+	if !required {
+		return nil
+	}
+	if err := mf.DropRequire(oldPath); err != nil {
+		return err
+	}
+	if err := mf.AddRequire(newPath, v.String()); err != nil {
+		return err
+	}
+	var old []modfile.Replace
+	for _, r := range mf.Replace {
+		if r.Old.Path == oldPath {
+			old = append(old, *r)
+		}
+	}
+	for _, r := range old {
+		if err := mf.DropReplace(r.Old.Path, r.Old.Version); err != nil {
+			return err
+		}
+	}
+	if len(old) == 0 {
+		return mf.AddReplace(newPath, "", localPath, "")
+	}
+	for _, r := range old {
+		// The old replacement's version, if any, is of the old path.
+		if err := mf.AddReplace(
+			newPath, "", r.New.Path, r.New.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
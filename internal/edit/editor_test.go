@@ -2,6 +2,11 @@ package edit
 
 import (
 	"testing"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+	"github.com/monopole/gorepomod/internal/testutil"
+	"golang.org/x/mod/modfile"
 )
 
 func TestUpstairs(t *testing.T) {
@@ -30,3 +35,187 @@ func TestUpstairs(t *testing.T) {
 		}
 	}
 }
+
+func makeModule(t *testing.T, name misc.ModuleShortName) misc.LaModule {
+	return testutil.NewModule(
+		t, name, semver.Zero(), "module "+testutil.ImportPath(name)+"\n")
+}
+
+func TestLocalPath(t *testing.T) {
+	var testCases = map[string]struct {
+		from     misc.ModuleShortName
+		to       misc.ModuleShortName
+		expected string
+	}{
+		"sibling": {
+			from:     "api",
+			to:       "kyaml",
+			expected: "../kyaml",
+		},
+		"from nested": {
+			from:     "cmd/config",
+			to:       "kyaml",
+			expected: "../../kyaml",
+		},
+		"from top": {
+			from:     misc.ModuleAtTop,
+			to:       "kyaml",
+			expected: "./kyaml",
+		},
+		"to top": {
+			from:     "cmd/config",
+			to:       misc.ModuleAtTop,
+			expected: "../..",
+		},
+	}
+	for n, tc := range testCases {
+		e := New(makeModule(t, tc.from), false)
+		actual := e.localPath(makeModule(t, tc.to))
+		if actual != tc.expected {
+			t.Errorf("%s: expected %q, got %q", n, tc.expected, actual)
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	mf, err := modfile.Parse("go.mod", []byte(`module gh.com/micheal/api
+
+go 1.15
+
+require (
+	// The kyaml module.
+	gh.com/micheal/kyaml v0.1.0
+	golang.org/x/mod v0.6.0
+)
+
+replace gh.com/micheal/kyaml v0.1.0 => ../kyaml
+
+replace golang.org/x/mod => ../../mod
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pin(mf, "gh.com/micheal/kyaml", semver.New(0, 2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	mf.Cleanup()
+	out, err := mf.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `module gh.com/micheal/api
+
+go 1.15
+
+require (
+	// The kyaml module.
+	gh.com/micheal/kyaml v0.2.0
+	golang.org/x/mod v0.6.0
+)
+
+replace golang.org/x/mod => ../../mod
+`
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "module m\n\ngo 1.15\n\nrequire (\n\ta v1\n\tb v1\n\tc v1\n\td v1\n\te v1\n\tf v1\n\tg v1\n\th v1\n)\n"
+	b := "module m\n\ngo 1.15\n\nrequire (\n\ta v1\n\tb v2\n\tc v1\n\td v1\n\te v1\n\tf v1\n\tg v1\n\th v1\n)\n\nreplace b => ../b\n"
+	expected := `--- a/api/go.mod
++++ b/api/go.mod
+@@ -4,7 +4,7 @@
+ 
+ require (
+ 	a v1
+-	b v1
++	b v2
+ 	c v1
+ 	d v1
+ 	e v1
+@@ -12,3 +12,5 @@
+ 	g v1
+ 	h v1
+ )
++
++replace b => ../b
+`
+	actual := unifiedDiff("api/go.mod", []byte(a), []byte(b))
+	if actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+	if d := unifiedDiff("go.mod", []byte(a), []byte(a)); d != "" {
+		t.Errorf("expected no diff, got\n%s", d)
+	}
+}
+
+func TestMoveDependency(t *testing.T) {
+	var testCases = map[string]struct {
+		gomod    string
+		expected string
+	}{
+		"notRequired": {
+			gomod: `module gh.com/micheal/api
+
+require golang.org/x/mod v0.6.0
+`,
+			expected: `module gh.com/micheal/api
+
+require golang.org/x/mod v0.6.0
+`,
+		},
+		"pinned": {
+			gomod: `module gh.com/micheal/api
+
+require (
+	gh.com/micheal/kyaml v1.4.0
+	golang.org/x/mod v0.6.0
+)
+`,
+			expected: `module gh.com/micheal/api
+
+require (
+	golang.org/x/mod v0.6.0
+	gh.com/micheal/kyaml/v2 v2.0.0
+)
+
+replace gh.com/micheal/kyaml/v2 => ../kyaml
+`,
+		},
+		"replaced": {
+			gomod: `module gh.com/micheal/api
+
+require gh.com/micheal/kyaml v1.4.0
+
+replace gh.com/micheal/kyaml v1.4.0 => ../../elsewhere/kyaml
+`,
+			expected: `module gh.com/micheal/api
+
+require gh.com/micheal/kyaml/v2 v2.0.0
+
+replace gh.com/micheal/kyaml/v2 => ../../elsewhere/kyaml
+`,
+		},
+	}
+	for n, tc := range testCases {
+		mf, err := modfile.Parse("go.mod", []byte(tc.gomod), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = moveDependency(
+			mf, "gh.com/micheal/kyaml", "gh.com/micheal/kyaml/v2",
+			semver.New(2, 0, 0), "../kyaml")
+		if err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+		mf.Cleanup()
+		out, err := mf.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", n, tc.expected, out)
+		}
+	}
+}
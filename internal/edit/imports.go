@@ -0,0 +1,124 @@
+package edit
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/utils"
+)
+
+// ImportRewriter returns the new import path for an import
+// path, and whether it changed.
+type ImportRewriter func(string) (string, bool)
+
+// RewriteImports rewrites the imports of the Go files in the
+// module's directory, skipping the directories of nested modules,
+// and vendor and testdata directories.  It returns the paths,
+// relative to the repository root, of the files changed, or
+// if doIt is false, of the files that would change, printing
+// the changes as unified diffs.
+func (e *Editor) RewriteImports(rw ImportRewriter) (changed []string, err error) {
+	root := e.module.AbsPath()
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p == root {
+				return nil
+			}
+			if n := info.Name(); n == "vendor" || n == "testdata" ||
+				strings.HasPrefix(n, ".") || strings.HasPrefix(n, "_") ||
+				utils.PathExists(filepath.Join(p, goModFile)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+		before, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		after, err := rewriteImports(p, before, rw)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(before, after) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if e.module.ShortName() != misc.ModuleAtTop {
+			rel = string(e.module.ShortName()) + "/" + rel
+		}
+		changed = append(changed, rel)
+		if !e.doIt {
+			fmt.Print(unifiedDiff(rel, before, after))
+			return nil
+		}
+		return ioutil.WriteFile(p, after, info.Mode())
+	})
+	return
+}
+
+// rewriteImports rewrites the import paths in the Go source,
+// leaving everything else alone.  If the source was formatted
+// as gofmt would, so is the result, so that import order holds.
+func rewriteImports(name string, src []byte, rw ImportRewriter) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(
+		fset, name, src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	type edit struct {
+		start, end int
+		path       string
+	}
+	var edits []edit
+	for _, imp := range f.Imports {
+		old, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		path, ok := rw(old)
+		if !ok {
+			continue
+		}
+		// Keep the quotes, be they " or `.
+		edits = append(edits, edit{
+			start: fset.Position(imp.Path.Pos()).Offset + 1,
+			end:   fset.Position(imp.Path.End()).Offset - 1,
+			path:  path,
+		})
+	}
+	if len(edits) == 0 {
+		return src, nil
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	result := append([]byte(nil), src...)
+	for _, ed := range edits {
+		result = append(result[:ed.start],
+			append([]byte(ed.path), result[ed.end:]...)...)
+	}
+	if formatted, err := format.Source(src); err != nil ||
+		!bytes.Equal(formatted, src) {
+		return result, nil
+	}
+	return format.Source(result)
+}
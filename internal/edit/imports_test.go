@@ -0,0 +1,69 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriteImports(t *testing.T) {
+	rw := func(p string) (string, bool) {
+		const old = "gh.com/micheal/kyaml"
+		if p != old && !strings.HasPrefix(p, old+"/") {
+			return p, false
+		}
+		return "gh.com/micheal/kyaml/v2" + p[len(old):], true
+	}
+	var testCases = map[string]struct {
+		src      string
+		expected string
+	}{
+		"noImports": {
+			src:      "package api\n",
+			expected: "package api\n",
+		},
+		"untouched": {
+			src:      "package api\n\nimport \"fmt\"\n",
+			expected: "package api\n\nimport \"fmt\"\n",
+		},
+		"formatted": {
+			src: `package api
+
+import (
+	"fmt"
+
+	"gh.com/micheal/kyaml"
+	kyfn "gh.com/micheal/kyaml/fn" // the functions
+	"gh.com/micheal/kyamlx"
+)
+
+var s = "gh.com/micheal/kyaml"
+`,
+			// Sorted, as gofmt would.
+			expected: `package api
+
+import (
+	"fmt"
+
+	"gh.com/micheal/kyaml/v2"
+	kyfn "gh.com/micheal/kyaml/v2/fn" // the functions
+	"gh.com/micheal/kyamlx"
+)
+
+var s = "gh.com/micheal/kyaml"
+`,
+		},
+		"unformatted": {
+			src:      "package api\nimport (\n  `gh.com/micheal/kyaml/fn`\n   \"fmt\"\n)\n",
+			expected: "package api\nimport (\n  `gh.com/micheal/kyaml/v2/fn`\n   \"fmt\"\n)\n",
+		},
+	}
+	for n, tc := range testCases {
+		actual, err := rewriteImports("x.go", []byte(tc.src), rw)
+		if err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+		if string(actual) != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", n, tc.expected, actual)
+		}
+	}
+}
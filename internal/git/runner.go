@@ -1,13 +1,18 @@
 package git
 
 import (
+	"archive/tar"
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os/exec"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/monopole/gorepomod/internal/misc"
-	"github.com/monopole/gorepomod/internal/semver"
+	"github.com/monopole/gorepomod/internal/naming"
 )
 
 const (
@@ -15,7 +20,8 @@ const (
 	pathSep        = "/"
 	remoteOrigin   = misc.TrackedRepo("origin")
 	remoteUpstream = misc.TrackedRepo("upstream")
-	mainBranch     = "master"
+	refsRemotes    = "refs/remotes/"
+	refsHeads      = "refs/heads/"
 	indent         = "  "
 	doing          = "  [x] "
 	faking         = "  [ ] "
@@ -37,7 +43,13 @@ const (
 	High
 )
 
-var recognizedRemotes = []misc.TrackedRepo{remoteUpstream, remoteOrigin}
+var (
+	recognizedRemotes = []misc.TrackedRepo{remoteUpstream, remoteOrigin}
+
+	// Main branch names to try, in order, if the remote
+	// doesn't say which branch is its default.
+	recognizedMainBranches = []string{"main", "master"}
+)
 
 // Runner runs specific git tasks using the git CLI.
 type Runner struct {
@@ -58,7 +70,7 @@ func NewQuiet(wd string, doIt bool) *Runner {
 }
 
 func newRunner(wd string, doIt bool, v Verbosity) *Runner {
-	return &Runner{workDir: wd, doIt: doIt, verbosity:v}
+	return &Runner{workDir: wd, doIt: doIt, verbosity: v}
 }
 
 func (gr *Runner) comment(f string) {
@@ -108,24 +120,80 @@ func (gr *Runner) runNoOut(s safetyLevel, args ...string) error {
 	return err
 }
 
-// TODO: allow for other remote names.
-func (gr *Runner) DetermineRemoteToUse() (misc.TrackedRepo, error) {
+// DetermineRemoteToUse returns the preferred remote, if not empty
+// and it exists.  Otherwise it returns the first recognized remote
+// found, or the only remote if there's just one.
+func (gr *Runner) DetermineRemoteToUse(
+	preferred misc.TrackedRepo) (misc.TrackedRepo, error) {
 	gr.comment("determining remote to use")
 	out, err := gr.run(noHarmDone, "remote")
 	if err != nil {
 		return "", err
 	}
-	remotes := strings.Split(out, "\n")
+	remotes := strings.Fields(out)
 	if len(remotes) < 1 {
 		return "", fmt.Errorf("need at least one remote")
 	}
+	if preferred != "" {
+		if contains(remotes, preferred) {
+			return preferred, nil
+		}
+		return "", fmt.Errorf(
+			"remote %q not found in %v", preferred, remotes)
+	}
 	for _, n := range recognizedRemotes {
 		if contains(remotes, n) {
 			return n, nil
 		}
 	}
+	if len(remotes) == 1 {
+		return misc.TrackedRepo(remotes[0]), nil
+	}
 	return "", fmt.Errorf(
-		"unable to find recognized remote %v", recognizedRemotes)
+		"unable to find recognized remote %v in %v; "+
+			"specify one with --remote", recognizedRemotes, remotes)
+}
+
+// DetermineMainBranch returns the remote's default branch, as
+// recorded in refs/remotes/{remote}/HEAD when the repository was
+// cloned (or by 'git remote set-head').  Failing that, it returns
+// the first recognized main branch name found locally or remotely.
+func (gr *Runner) DetermineMainBranch(
+	remote misc.TrackedRepo) (string, error) {
+	gr.comment("determining main branch")
+	out, err := gr.run(
+		noHarmDone, "symbolic-ref", refsRemotes+string(remote)+"/HEAD")
+	if err == nil {
+		ref := strings.TrimSpace(out)
+		prefix := refsRemotes + string(remote) + pathSep
+		if strings.HasPrefix(ref, prefix) {
+			return ref[len(prefix):], nil
+		}
+	}
+	for _, b := range recognizedMainBranches {
+		for _, ref := range []string{
+			refsHeads + b,
+			refsRemotes + string(remote) + pathSep + b} {
+			if gr.runNoOut(
+				noHarmDone, "rev-parse", "--verify", "--quiet", ref) == nil {
+				return b, nil
+			}
+		}
+	}
+	return "", fmt.Errorf(
+		"unable to determine the main branch of remote %q; "+
+			"tried %v; specify one with --mainBranch",
+		remote, recognizedMainBranches)
+}
+
+// RemoteURL returns the URL of the given remote.
+func (gr *Runner) RemoteURL(remote misc.TrackedRepo) (string, error) {
+	gr.comment("getting remote url")
+	out, err := gr.run(noHarmDone, "remote", "get-url", string(remote))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
 }
 
 func contains(list []string, item misc.TrackedRepo) bool {
@@ -137,7 +205,9 @@ func contains(list []string, item misc.TrackedRepo) bool {
 	return false
 }
 
-func (gr *Runner) LoadLocalTags() (result misc.VersionMap, err error) {
+// LoadLocalTags loads the local tags recognized by the matcher.
+func (gr *Runner) LoadLocalTags(
+	tm *naming.TagMatcher) (result misc.VersionMap, err error) {
 	gr.comment("loading local tags")
 	var out string
 	out, err = gr.run(noHarmDone, "tag", "-l")
@@ -147,7 +217,7 @@ func (gr *Runner) LoadLocalTags() (result misc.VersionMap, err error) {
 	result = make(misc.VersionMap)
 	lines := strings.Split(out, "\n")
 	for _, l := range lines {
-		n, v, err := parseModuleSpec(l)
+		n, v, err := tm.Match(l)
 		if err != nil {
 			// ignore it
 			continue
@@ -160,8 +230,10 @@ func (gr *Runner) LoadLocalTags() (result misc.VersionMap, err error) {
 	return
 }
 
+// LoadRemoteTags loads the remote's tags recognized by the matcher.
 func (gr *Runner) LoadRemoteTags(
-	remote misc.TrackedRepo) (result misc.VersionMap, err error) {
+	remote misc.TrackedRepo,
+	tm *naming.TagMatcher) (result misc.VersionMap, err error) {
 	gr.comment("loading remote tags")
 	var out string
 	out, err = gr.run(noHarmDone, "ls-remote", "--ref", string(remote))
@@ -181,7 +253,7 @@ func (gr *Runner) LoadRemoteTags(
 			continue
 		}
 		path := fields[1][len(refsTags):]
-		n, v, err := parseModuleSpec(path)
+		n, v, err := tm.Match(path)
 		if err != nil {
 			// ignore it
 			continue
@@ -194,22 +266,6 @@ func (gr *Runner) LoadRemoteTags(
 	return
 }
 
-func parseModuleSpec(
-	path string) (n misc.ModuleShortName, v semver.SemVer, err error) {
-	fields := strings.Split(path, pathSep)
-	v, err = semver.Parse(fields[len(fields)-1])
-	if err != nil {
-		// Silently ignore versions we don't understand.
-		return "", v, err
-	}
-	n = misc.ModuleAtTop
-	if len(fields) > 1 {
-		n = misc.ModuleShortName(
-			strings.Join(fields[:len(fields)-1], pathSep))
-	}
-	return
-}
-
 func (gr *Runner) Debug(remote misc.TrackedRepo) error {
 	return nil // gr.CheckoutMainBranch(remote)
 }
@@ -226,7 +282,7 @@ func (gr *Runner) AssureCleanWorkspace() error {
 	return nil
 }
 
-func (gr *Runner) AssureOnMainBranch() error {
+func (gr *Runner) AssureOnMainBranch(mainBranch string) error {
 	gr.comment("assuring main branch checked out")
 	out, err := gr.run(noHarmDone, "status")
 	if err != nil {
@@ -239,7 +295,7 @@ func (gr *Runner) AssureOnMainBranch() error {
 }
 
 // CheckoutMainBranch does that.
-func (gr *Runner) CheckoutMainBranch() error {
+func (gr *Runner) CheckoutMainBranch(mainBranch string) error {
 	gr.comment("checking out main branch")
 	return gr.runNoOut(noHarmDone, "checkout", mainBranch)
 }
@@ -250,12 +306,24 @@ func (gr *Runner) FetchRemote(remote misc.TrackedRepo) error {
 	return gr.runNoOut(noHarmDone, "fetch", string(remote))
 }
 
+// RemoteBranch names the remote tracking branch, e.g. origin/main.
+func RemoteBranch(remote misc.TrackedRepo, branch string) string {
+	return strings.Join([]string{string(remote), branch}, pathSep)
+}
+
 // MergeFromRemoteMain does a fast forward only merge with main branch.
-func (gr *Runner) MergeFromRemoteMain(remote misc.TrackedRepo) error {
-	remo := strings.Join(
-		[]string{string(remote), mainBranch}, pathSep)
+func (gr *Runner) MergeFromRemoteMain(
+	remote misc.TrackedRepo, mainBranch string) error {
 	gr.comment("merging from remote")
-	return gr.runNoOut(undoPainful, "merge", "--ff-only", remo)
+	return gr.runNoOut(
+		undoPainful, "merge", "--ff-only", RemoteBranch(remote, mainBranch))
+}
+
+// MergeFromMain does a fast forward only merge with the local
+// main branch, which may hold commits not yet pushed.
+func (gr *Runner) MergeFromMain(mainBranch string) error {
+	gr.comment("merging from main branch")
+	return gr.runNoOut(undoPainful, "merge", "--ff-only", mainBranch)
 }
 
 // CheckoutReleaseBranch attempts to checkout or create a branch.
@@ -277,6 +345,10 @@ func (gr *Runner) CheckoutReleaseBranch(
 		}
 		return nil
 	}
+	if gr.LocalBranchExists(branch) {
+		// Created, but not pushed, by an earlier attempt.
+		return gr.CheckoutBranch(branch)
+	}
 	gr.comment("creating branch")
 	// The branch doesn't exist.  Create it.
 	out, err := gr.run(noHarmDone, "checkout", "-b", branch)
@@ -289,6 +361,161 @@ func (gr *Runner) CheckoutReleaseBranch(
 	return nil
 }
 
+// CurrentBranch returns the name of the checked out branch.
+func (gr *Runner) CurrentBranch() (string, error) {
+	gr.comment("determining current branch")
+	out, err := gr.run(noHarmDone, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// LocalBranchExists does that.
+func (gr *Runner) LocalBranchExists(branch string) bool {
+	return gr.runNoOut(
+		noHarmDone, "rev-parse", "--verify", "--quiet", refsHeads+branch) == nil
+}
+
+// Head returns the hash of the checked out commit.
+func (gr *Runner) Head() (string, error) {
+	out, err := gr.run(noHarmDone, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// AddWorktree checks out the commit, detached,
+// in a new work tree in the directory.
+func (gr *Runner) AddWorktree(dir, commit string) error {
+	gr.comment("adding work tree for " + commit)
+	return gr.runNoOut(noHarmDone, "worktree", "add", "--detach", dir, commit)
+}
+
+// RemoveWorktree removes the work tree in the directory.
+func (gr *Runner) RemoveWorktree(dir string) error {
+	return gr.runNoOut(noHarmDone, "worktree", "remove", "--force", dir)
+}
+
+// TagsContaining returns the local tags of commits
+// having the given commit as an ancestor, or being it.
+func (gr *Runner) TagsContaining(commit string) ([]string, error) {
+	gr.comment("finding tags containing " + commit)
+	out, err := gr.run(noHarmDone, "tag", "--contains", commit)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// BranchHead returns the hash of the commit the local branch points to.
+func (gr *Runner) BranchHead(branch string) (string, error) {
+	out, err := gr.run(noHarmDone, "rev-parse", refsHeads+branch)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// SetBranch points the local branch, which mustn't be
+// checked out, at the given commit.
+func (gr *Runner) SetBranch(branch, commit string) error {
+	gr.comment("restoring branch " + branch)
+	return gr.runNoOut(undoPainful, "branch", "-f", branch, commit)
+}
+
+// ResetHard points the current branch, index and working tree
+// at the given commit.
+func (gr *Runner) ResetHard(commit string) error {
+	gr.comment("resetting to " + commit)
+	return gr.runNoOut(undoPainful, "reset", "--hard", commit)
+}
+
+// GitDir returns the absolute path to the repository's git
+// directory, which, in a work tree, isn't necessarily ".git".
+func (gr *Runner) GitDir() (string, error) {
+	out, err := gr.run(noHarmDone, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CheckoutBranch checks out an existing local branch.
+func (gr *Runner) CheckoutBranch(branch string) error {
+	gr.comment("checking out " + branch)
+	return gr.runNoOut(noHarmDone, "checkout", branch)
+}
+
+// CheckoutBranchFrom checks out the branch, fast forwarding it to
+// the remote's branch if there is one.  If the branch exists
+// neither locally nor on the remote, it's created at start,
+// and created is true.
+func (gr *Runner) CheckoutBranchFrom(
+	remote misc.TrackedRepo,
+	branch, start string) (created bool, err error) {
+	onRemote, err := gr.doesRemoteBranchExist(remote, branch)
+	if err != nil {
+		return false, err
+	}
+	if onRemote {
+		gr.comment("checking out branch")
+		// Creates a tracking branch if there's no local one.
+		if err = gr.runNoOut(noHarmDone, "checkout", branch); err != nil {
+			return false, err
+		}
+		gr.comment("merging from remote branch")
+		err = gr.runNoOut(
+			undoPainful, "merge", "--ff-only", RemoteBranch(remote, branch))
+		if err != nil {
+			return false, gr.explainDivergence(remote, branch)
+		}
+		return false, nil
+	}
+	if gr.LocalBranchExists(branch) {
+		return false, gr.CheckoutBranch(branch)
+	}
+	gr.comment("creating branch at " + start)
+	return true, gr.runNoOut(undoPainful, "checkout", "-b", branch, start)
+}
+
+// DeleteLocalBranch does that.
+func (gr *Runner) DeleteLocalBranch(branch string) error {
+	gr.comment("deleting local branch")
+	return gr.runNoOut(undoPainful, "branch", "-D", branch)
+}
+
+// CherryPick applies the commits to the current branch, noting
+// each original commit in the new commit's message.
+// If a commit doesn't apply cleanly, the cherry-pick is aborted,
+// restoring the branch, and the error names the commit and
+// the conflicting files.
+func (gr *Runner) CherryPick(commits []string) error {
+	gr.comment("cherry-picking")
+	err := gr.runNoOut(
+		undoPainful, append([]string{"cherry-pick", "-x"}, commits...)...)
+	if err == nil {
+		return nil
+	}
+	commit, _ := gr.run(noHarmDone, "rev-parse", "--short", "CHERRY_PICK_HEAD")
+	files, _ := gr.run(
+		noHarmDone, "diff", "--name-only", "--diff-filter=U")
+	gr.comment("aborting cherry-pick")
+	if abortErr := gr.runNoOut(
+		noHarmDone, "cherry-pick", "--abort"); abortErr != nil {
+		return fmt.Errorf(
+			"%v; unable to abort the cherry-pick (%v); "+
+				"try 'git cherry-pick --abort'", err, abortErr)
+	}
+	if strings.TrimSpace(files) == "" {
+		return err
+	}
+	return fmt.Errorf(
+		"cherry-pick of %s conflicts in %v; aborted",
+		strings.TrimSpace(commit), strings.Fields(files))
+}
+
 func (gr *Runner) doesRemoteBranchExist(
 	remote misc.TrackedRepo, branch string) (bool, error) {
 	gr.comment("looking for branch on remote")
@@ -306,20 +533,282 @@ func (gr *Runner) doesRemoteBranchExist(
 	return false, nil
 }
 
+// PushBranchToRemote pushes the branch, refusing to if that
+// would discard commits on the remote's branch, i.e. if the
+// remote's branch, as last fetched, isn't an ancestor of the
+// local branch.  Git itself refuses a push that isn't a fast
+// forward, in case the remote changed since the fetch.
 func (gr *Runner) PushBranchToRemote(
 	remote misc.TrackedRepo, branch string) error {
+	if err := gr.assureFastForward(remote, branch); err != nil {
+		return err
+	}
 	gr.comment("pushing branch to remote")
-	return gr.runNoOut(undoPainful, "push", "-f", string(remote), branch)
+	return gr.runNoOut(undoPainful, "push", string(remote), branch)
 }
 
-func (gr *Runner) CreateLocalReleaseTag(tag, branch string) error {
-	msg := fmt.Sprintf("\"Release %s on branch %s\"", tag, branch)
-	gr.comment("creating local release tag")
+// assureFastForward returns an error explaining the divergence
+// if pushing the local branch wouldn't fast forward the remote's.
+func (gr *Runner) assureFastForward(
+	remote misc.TrackedRepo, branch string) error {
+	theirs := refsRemotes + RemoteBranch(remote, branch)
+	ours := refsHeads + branch
+	if gr.runNoOut(
+		noHarmDone, "rev-parse", "--verify", "--quiet", theirs) != nil {
+		// Nothing on the remote to lose.
+		return nil
+	}
+	if gr.runNoOut(
+		noHarmDone, "rev-parse", "--verify", "--quiet", ours) != nil {
+		// Nothing to compare, e.g. the branch's creation was
+		// only printed; let the push itself complain.
+		return nil
+	}
+	gr.comment("assuring push is a fast forward")
+	if gr.runNoOut(
+		noHarmDone, "merge-base", "--is-ancestor", theirs, ours) == nil {
+		return nil
+	}
+	return fmt.Errorf(
+		"refusing to push, which would discard remote commits; %v",
+		gr.explainDivergence(remote, branch))
+}
+
+// explainDivergence returns an error describing how the local
+// branch and the remote's branch have diverged.
+func (gr *Runner) explainDivergence(
+	remote misc.TrackedRepo, branch string) error {
+	theirs := refsRemotes + RemoteBranch(remote, branch)
+	ours := refsHeads + branch
+	out, err := gr.run(
+		noHarmDone, "rev-list", "--left-right", "--count", ours+"..."+theirs)
+	if err != nil {
+		return err
+	}
+	counts := strings.Fields(out)
+	if len(counts) != 2 {
+		return fmt.Errorf("unexpected rev-list output %q", out)
+	}
+	return fmt.Errorf(
+		"branch %s has diverged from %s: %s local commit(s) aren't on the "+
+			"remote, and %s remote commit(s) aren't local; reconcile the "+
+			"branches (e.g. 'git checkout %s && git rebase %s'), "+
+			"then try again",
+		branch, RemoteBranch(remote, branch), counts[0], counts[1],
+		branch, RemoteBranch(remote, branch))
+}
+
+// PushMainBranchToRemote pushes the main branch, refusing
+// anything but a fast forward of the remote branch.
+func (gr *Runner) PushMainBranchToRemote(
+	remote misc.TrackedRepo, mainBranch string) error {
+	gr.comment("pushing main branch to remote")
+	return gr.runNoOut(undoPainful, "push", string(remote), mainBranch)
+}
+
+// CommitFiles commits the files, whether tracked or not.
+func (gr *Runner) CommitFiles(msg string, paths ...string) error {
+	gr.comment("committing " + strings.Join(paths, ", "))
+	if err := gr.runNoOut(
+		undoPainful, append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
 	return gr.runNoOut(
-		undoPainful,
-		"tag", "-a",
-		"-m", msg,
-		tag)
+		undoPainful, append([]string{"commit", "-m", msg, "--"}, paths...)...)
+}
+
+// LogEntry is a commit as reported by Log.
+type LogEntry struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+	Body    string `json:"body,omitempty"`
+}
+
+// Log returns the commits, newest first, reachable from the
+// commit but not from the tag, that touch files selected by
+// the pathspecs, omitting merges.  An empty tag means all
+// commits reachable from the commit.
+func (gr *Runner) Log(
+	tag, commit string, pathSpecs []string) ([]LogEntry, error) {
+	gr.comment("reading log since " + tag)
+	rng := commit
+	if tag != "" {
+		rng = tag + ".." + commit
+	}
+	// Fields end with NUL, commits with RS.
+	out, err := gr.run(
+		noHarmDone,
+		append([]string{
+			"log", "--no-merges", "--format=%H%x00%s%x00%b%x00%x1e", rng, "--"},
+			pathSpecs...)...)
+	if err != nil {
+		return nil, err
+	}
+	var result []LogEntry
+	for _, rec := range strings.Split(out, "\x1e") {
+		f := strings.Split(strings.TrimLeft(rec, "\n"), "\x00")
+		if len(f) < 3 {
+			continue
+		}
+		result = append(result, LogEntry{
+			Hash:    f[0],
+			Subject: f[1],
+			Body:    strings.TrimSpace(f[2]),
+		})
+	}
+	return result, nil
+}
+
+// HasChangesSince reports whether any of the files selected
+// by the given pathspecs differ between the tag and HEAD.
+// An empty tag means compare against the empty tree, i.e.
+// everything tracked counts as a change.
+func (gr *Runner) HasChangesSince(tag string, pathSpecs []string) (bool, error) {
+	gr.comment("looking for changes since " + tag)
+	args := []string{"diff", "--name-only"}
+	if tag == "" {
+		args = []string{"ls-files"}
+	} else {
+		args = append(args, tag, "HEAD")
+	}
+	out, err := gr.run(noHarmDone, append(append(args, "--"), pathSpecs...)...)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// CountCommitsSince counts the commits reachable from HEAD but
+// not from the tag that touch files selected by the pathspecs.
+// An empty tag means count all commits reachable from HEAD.
+func (gr *Runner) CountCommitsSince(tag string, pathSpecs []string) (int, error) {
+	gr.comment("counting commits since " + tag)
+	rng := "HEAD"
+	if tag != "" {
+		rng = tag + "..HEAD"
+	}
+	out, err := gr.run(
+		noHarmDone,
+		append([]string{"rev-list", "--count", rng, "--"}, pathSpecs...)...)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// FilesAt returns the content of the files selected by the pathspecs
+// as of the given tag, keyed by path relative to the repository root.
+func (gr *Runner) FilesAt(
+	tag string, pathSpecs []string) (map[string][]byte, error) {
+	gr.comment("reading files at " + tag)
+	c := exec.Command(
+		"git",
+		append([]string{"archive", "--format=tar", tag, "--"}, pathSpecs...)...)
+	c.Dir = gr.workDir
+	gr.doing(c.String())
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s; unable to archive %s", err.Error(), tag)
+	}
+	result := make(map[string][]byte)
+	tr := tar.NewReader(bytes.NewReader(out))
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		result[h.Name] = content
+	}
+}
+
+// Signing says how to sign tags.
+type Signing struct {
+	// Format is "gpg", "ssh" or "x509", or empty for unsigned
+	// tags, unless Key is given, in which case git's gpg.format
+	// setting holds.
+	Format string `json:"format,omitempty"`
+	// Key is the signing key, or empty for git's user.signingKey.
+	Key string `json:"key,omitempty"`
+}
+
+// IsSigned is true if tags are to be signed.
+func (s Signing) IsSigned() bool {
+	return s.Format != "" || s.Key != ""
+}
+
+// tagArgs returns the git arguments creating a tag so signed.
+func (s Signing) tagArgs() []string {
+	if !s.IsSigned() {
+		return []string{"tag", "-a"}
+	}
+	// Without a format, git's own gpg.format setting holds.
+	var args []string
+	switch s.Format {
+	case "":
+	case "gpg":
+		args = []string{"-c", "gpg.format=openpgp"}
+	default:
+		args = []string{"-c", "gpg.format=" + s.Format}
+	}
+	if s.Key == "" {
+		return append(args, "tag", "-s")
+	}
+	return append(args, "tag", "-u", s.Key)
+}
+
+// CreateLocalReleaseTag creates an annotated tag with the
+// message, signed as specified, on the given commit (e.g.
+// origin/main), or on HEAD if commit is empty.
+func (gr *Runner) CreateLocalReleaseTag(
+	tag, msg, commit string, sign Signing) error {
+	if sign.IsSigned() {
+		gr.comment("creating signed local release tag")
+	} else {
+		gr.comment("creating local release tag")
+	}
+	args := append(sign.tagArgs(), "-m", msg, tag)
+	if commit != "" {
+		args = append(args, commit)
+	}
+	return gr.runNoOut(undoPainful, args...)
+}
+
+// VerifyTag checks the signature of the tag, going by the exit
+// status of 'git tag -v', returning the verifier's report of a
+// good signature, or the gist of the problem.
+func (gr *Runner) VerifyTag(tag string) (string, error) {
+	gr.comment("verifying tag signature")
+	c := exec.Command("git", "tag", "-v", tag)
+	c.Dir = gr.workDir
+	gr.doing(c.String())
+	// The tag goes to stdout, the verifier's report to stderr.
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	err := c.Run()
+	var lines []string
+	for _, l := range strings.Split(stderr.String(), "\n") {
+		if l = strings.TrimSpace(strings.TrimPrefix(l, "gpg:")); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if err == nil {
+		return strings.Join(lines, "; "), nil
+	}
+	if len(lines) == 0 {
+		return "", err
+	}
+	// The last line usually says what's wrong.
+	return "", fmt.Errorf("%s", lines[len(lines)-1])
 }
 
 func (gr *Runner) DeleteLocalTag(tag string) error {
@@ -0,0 +1,341 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/misc"
+)
+
+func TestSigningTagArgs(t *testing.T) {
+	var testCases = map[string]struct {
+		sign     Signing
+		expected []string
+	}{
+		"unsigned": {
+			expected: []string{"tag", "-a"},
+		},
+		"gpgDefaultKey": {
+			sign:     Signing{Format: "gpg"},
+			expected: []string{"-c", "gpg.format=openpgp", "tag", "-s"},
+		},
+		"keyOnly": {
+			sign:     Signing{Key: "ABCD1234"},
+			expected: []string{"tag", "-u", "ABCD1234"},
+		},
+		"ssh": {
+			sign:     Signing{Format: "ssh", Key: "/keys/id_ed25519"},
+			expected: []string{"-c", "gpg.format=ssh", "tag", "-u", "/keys/id_ed25519"},
+		},
+	}
+	for n, tc := range testCases {
+		if actual := tc.sign.tagArgs(); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", n, tc.expected, actual)
+		}
+	}
+}
+
+// run runs the command in the directory, failing the test on error.
+func run(t *testing.T, dir string, args ...string) string {
+	c := exec.Command(args[0], args[1:]...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// newRepo returns a repository with one commit,
+// in a temporary directory the caller must remove.
+func newRepo(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, dir, "git", "init", "-q")
+	run(t, dir, "git", "config", "user.name", "Test")
+	run(t, dir, "git", "config", "user.email", "test@example.com")
+	run(t, dir, "git", "commit", "-q", "--allow-empty", "-m", "initial")
+	return dir
+}
+
+func TestSignedTagsGpg(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	home, err := ioutil.TempDir("", "gnupg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	// A throwaway key, used by git's gpg via GNUPGHOME.
+	if old, ok := os.LookupEnv("GNUPGHOME"); ok {
+		defer os.Setenv("GNUPGHOME", old)
+	} else {
+		defer os.Unsetenv("GNUPGHOME")
+	}
+	os.Setenv("GNUPGHOME", home)
+	run(t, home, "gpg", "--batch", "--pinentry-mode", "loopback",
+		"--passphrase", "", "--quick-gen-key",
+		"Test <test@example.com>", "default", "default", "never")
+	defer exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+
+	dir := newRepo(t)
+	defer os.RemoveAll(dir)
+	gr := NewQuiet(dir, true)
+	err = gr.CreateLocalReleaseTag(
+		"v1.0.0", "Release v1.0.0", "", Signing{Key: "test@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := gr.VerifyTag("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(desc, "Test <test@example.com>") {
+		t.Errorf("unexpected signature description %q", desc)
+	}
+	if err = gr.CreateLocalReleaseTag("v1.0.1", "Release v1.0.1", "", Signing{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gr.VerifyTag("v1.0.1"); err == nil {
+		t.Errorf("expected unsigned tag to fail verification")
+	}
+}
+
+func TestSignedTagsSsh(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	dir := newRepo(t)
+	defer os.RemoveAll(dir)
+	keys, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(keys)
+	key := filepath.Join(keys, "id_ed25519")
+	run(t, keys, "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key)
+	pub, err := ioutil.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := filepath.Join(keys, "allowed_signers")
+	err = ioutil.WriteFile(
+		signers, []byte("test@example.com "+string(pub)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, dir, "git", "config", "gpg.ssh.allowedSignersFile", signers)
+
+	gr := NewQuiet(dir, true)
+	err = gr.CreateLocalReleaseTag(
+		"v1.0.0", "Release v1.0.0", "", Signing{Format: "ssh", Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := gr.VerifyTag("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(desc, "test@example.com") {
+		t.Errorf("unexpected signature description %q", desc)
+	}
+}
+
+func TestPushBranchToRemote(t *testing.T) {
+	var testCases = map[string]struct {
+		// The remote's branch moves ahead of the local one.
+		diverge bool
+		// The local repo knows, having fetched.
+		fetch  bool
+		errMsg string
+	}{
+		"fastForward": {},
+		"diverged": {
+			diverge: true,
+			fetch:   true,
+			errMsg:  "refusing to push, which would discard remote commits",
+		},
+		"divergedUnfetched": {
+			diverge: true,
+			errMsg:  "rejected",
+		},
+	}
+	for n, tc := range testCases {
+		dir := newRepo(t)
+		remote := dir + ".git"
+		run(t, dir, "git", "init", "-q", "--bare", remote)
+		run(t, dir, "git", "remote", "add", "origin", remote)
+		run(t, dir, "git", "checkout", "-q", "-b", "release-v1.2")
+		run(t, dir, "git", "push", "-q", "origin", "release-v1.2")
+		if tc.diverge {
+			other := dir + "-other"
+			run(t, dir, "git", "clone", "-q", "-b", "release-v1.2", remote, other)
+			run(t, other, "git", "-c", "user.name=Other",
+				"-c", "user.email=other@example.com",
+				"commit", "-q", "--allow-empty", "-m", "remote fix")
+			run(t, other, "git", "push", "-q", "origin", "release-v1.2")
+			os.RemoveAll(other)
+		}
+		remoteHead := strings.TrimSpace(
+			run(t, remote, "git", "rev-parse", "release-v1.2"))
+		if tc.fetch {
+			run(t, dir, "git", "fetch", "-q", "origin")
+		}
+		run(t, dir, "git", "commit", "-q", "--allow-empty", "-m", "local fix")
+
+		err := NewQuiet(dir, true).PushBranchToRemote("origin", "release-v1.2")
+		actual := strings.TrimSpace(
+			run(t, remote, "git", "rev-parse", "release-v1.2"))
+		if tc.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			}
+			local := strings.TrimSpace(run(t, dir, "git", "rev-parse", "HEAD"))
+			if actual != local {
+				t.Errorf("%s: expected remote at %s, got %s", n, local, actual)
+			}
+		} else {
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Errorf("%s: expected error containing %q, got %v",
+					n, tc.errMsg, err)
+			}
+			if actual != remoteHead {
+				t.Errorf("%s: expected remote unchanged at %s, got %s",
+					n, remoteHead, actual)
+			}
+		}
+		os.RemoveAll(dir)
+		os.RemoveAll(remote)
+	}
+}
+
+func TestDetermineRemoteToUse(t *testing.T) {
+	var testCases = map[string]struct {
+		remotes   []string
+		preferred string
+		expected  string
+		errMsg    string
+	}{
+		"none": {
+			errMsg: "need at least one remote",
+		},
+		"onlyOne": {
+			remotes:  []string{"fork"},
+			expected: "fork",
+		},
+		"origin": {
+			remotes:  []string{"fork", "origin"},
+			expected: "origin",
+		},
+		"upstreamBeforeOrigin": {
+			remotes:  []string{"origin", "upstream"},
+			expected: "upstream",
+		},
+		"preferred": {
+			remotes:   []string{"fork", "origin", "upstream"},
+			preferred: "fork",
+			expected:  "fork",
+		},
+		"preferredMissing": {
+			remotes:   []string{"origin", "upstream"},
+			preferred: "fork",
+			errMsg:    `remote "fork" not found`,
+		},
+		"unrecognized": {
+			remotes: []string{"fork", "mirror"},
+			errMsg:  "unable to find recognized remote",
+		},
+	}
+	for n, tc := range testCases {
+		dir := newRepo(t)
+		for _, r := range tc.remotes {
+			run(t, dir, "git", "remote", "add", r, "https://example.com/"+r)
+		}
+		actual, err := NewQuiet(dir, true).DetermineRemoteToUse(
+			misc.TrackedRepo(tc.preferred))
+		if tc.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			} else if string(actual) != tc.expected {
+				t.Errorf("%s: expected %s, got %s", n, tc.expected, actual)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("%s: expected error containing %q, got %v",
+				n, tc.errMsg, err)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestDetermineMainBranch(t *testing.T) {
+	var testCases = map[string]struct {
+		// The branch checked out locally.
+		local string
+		// If not empty, the local branch is pushed
+		// to the remote under this name.
+		remote string
+		// The remote's HEAD is recorded, as when cloning.
+		head     bool
+		expected string
+		errMsg   string
+	}{
+		"main": {
+			local:    "main",
+			expected: "main",
+		},
+		"onlyMaster": {
+			local:    "master",
+			expected: "master",
+		},
+		"remoteMaster": {
+			local:    "feature",
+			remote:   "master",
+			expected: "master",
+		},
+		"remoteHead": {
+			local:    "main",
+			remote:   "develop",
+			head:     true,
+			expected: "develop",
+		},
+		"unrecognized": {
+			local:  "trunk",
+			errMsg: "unable to determine the main branch",
+		},
+	}
+	for n, tc := range testCases {
+		dir := newRepo(t)
+		remote := dir + ".git"
+		run(t, dir, "git", "branch", "-m", tc.local)
+		run(t, dir, "git", "init", "-q", "--bare", remote)
+		run(t, dir, "git", "remote", "add", "origin", remote)
+		if tc.remote != "" {
+			run(t, dir, "git", "push", "-q", "origin", tc.local+":"+tc.remote)
+			run(t, dir, "git", "fetch", "-q", "origin")
+		}
+		if tc.head {
+			run(t, dir, "git", "remote", "set-head", "origin", tc.remote)
+		}
+		actual, err := NewQuiet(dir, true).DetermineMainBranch("origin")
+		if tc.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			} else if actual != tc.expected {
+				t.Errorf("%s: expected %s, got %s", n, tc.expected, actual)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("%s: expected error containing %q, got %v",
+				n, tc.errMsg, err)
+		}
+		os.RemoveAll(dir)
+		os.RemoveAll(remote)
+	}
+}
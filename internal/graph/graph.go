@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/misc"
+)
+
+// Format is a graph description language.
+type Format string
+
+const (
+	Dot     = Format("dot")
+	Mermaid = Format("mermaid")
+)
+
+// ParseFormat converts a string to a Format.
+func ParseFormat(raw string) (Format, error) {
+	switch f := Format(raw); f {
+	case Dot, Mermaid:
+		return f, nil
+	}
+	return Dot, fmt.Errorf(
+		"unknown graph format %q; specify %q or %q", raw, Dot, Mermaid)
+}
+
+// edge is a requirement of one module on another.
+type edge struct {
+	from, to int
+	label    string
+	unPinned bool
+}
+
+// Write writes the intra-repo dependency graph of the modules.
+// Edges point from a module to the modules it requires, and
+// are labelled with the required version.  Edges satisfied by
+// a local replacement (unpinned) are drawn dashed.
+func Write(w io.Writer, modules misc.LesModules, f Format) error {
+	index := make(map[misc.ModuleShortName]int)
+	for i, m := range modules {
+		index[m.ShortName()] = i
+	}
+	var edges []edge
+	for i, m := range modules {
+		for _, dep := range modules.InternalDeps(m) {
+			e := edge{
+				from:     i,
+				to:       index[dep.M.ShortName()],
+				label:    dep.V.String(),
+				unPinned: m.IsUnPinned(dep.M),
+			}
+			if e.unPinned {
+				e.label += " (unpinned)"
+			}
+			edges = append(edges, e)
+		}
+	}
+	var b strings.Builder
+	switch f {
+	case Dot:
+		writeDot(&b, modules, edges)
+	case Mermaid:
+		writeMermaid(&b, modules, edges)
+	default:
+		return fmt.Errorf("cannot write format %q", f)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func nodeLabel(m misc.LaModule) string {
+	if v := m.VersionLocal().Pretty(); v != "" {
+		return string(m.ShortName()) + " " + v
+	}
+	return string(m.ShortName())
+}
+
+func writeDot(
+	b *strings.Builder, modules misc.LesModules, edges []edge) {
+	b.WriteString("digraph modules {\n")
+	b.WriteString("  node [shape=box];\n")
+	for i, m := range modules {
+		fmt.Fprintf(b, "  n%d [label=%q];\n", i, nodeLabel(m))
+	}
+	for _, e := range edges {
+		style := ""
+		if e.unPinned {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(
+			b, "  n%d -> n%d [label=%q%s];\n", e.from, e.to, e.label, style)
+	}
+	b.WriteString("}\n")
+}
+
+func writeMermaid(
+	b *strings.Builder, modules misc.LesModules, edges []edge) {
+	b.WriteString("graph LR\n")
+	for i, m := range modules {
+		fmt.Fprintf(b, "  n%d[\"%s\"]\n", i, nodeLabel(m))
+	}
+	for _, e := range edges {
+		arrow := "-->"
+		if e.unPinned {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(b, "  n%d %s|\"%s\"| n%d\n", e.from, arrow, e.label, e.to)
+	}
+}
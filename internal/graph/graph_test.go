@@ -0,0 +1,64 @@
+package graph_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/graph"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+	"github.com/monopole/gorepomod/internal/testutil"
+)
+
+func TestWrite(t *testing.T) {
+	modules := misc.LesModules{
+		testutil.NewModule(t, "api", semver.New(0, 2, 0), `
+module gh.com/micheal/api
+require gh.com/micheal/kyaml v0.1.0
+`),
+		testutil.NewModule(t, "cmd/config", semver.Zero(), `
+module gh.com/micheal/cmd/config
+require gh.com/micheal/kyaml v0.1.0
+replace gh.com/micheal/kyaml v0.1.0 => ../../kyaml
+`),
+		testutil.NewModule(t, "kyaml", semver.New(0, 1, 0), `
+module gh.com/micheal/kyaml
+`),
+	}
+	var testCases = map[string]struct {
+		format   graph.Format
+		expected string
+	}{
+		"dot": {
+			format: graph.Dot,
+			expected: `digraph modules {
+  node [shape=box];
+  n0 [label="api v0.2.0"];
+  n1 [label="cmd/config"];
+  n2 [label="kyaml v0.1.0"];
+  n0 -> n2 [label="v0.1.0"];
+  n1 -> n2 [label="v0.1.0 (unpinned)", style=dashed];
+}
+`,
+		},
+		"mermaid": {
+			format: graph.Mermaid,
+			expected: `graph LR
+  n0["api v0.2.0"]
+  n1["cmd/config"]
+  n2["kyaml v0.1.0"]
+  n0 -->|"v0.1.0"| n2
+  n1 -.->|"v0.1.0 (unpinned)"| n2
+`,
+		},
+	}
+	for n, tc := range testCases {
+		var b bytes.Buffer
+		if err := graph.Write(&b, modules, tc.format); err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+		if b.String() != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", n, tc.expected, b.String())
+		}
+	}
+}
@@ -34,8 +34,12 @@ type LaModule interface {
 	// import the module.
 	ImportPath() string
 
-	// AbsPath is the absolute path to the module's
-	// go.mod file on the local file system.
+	// PathMajor is the major version required by the import
+	// path's suffix, e.g. 2 for .../kyaml/v2, or 0 if none.
+	PathMajor() int
+
+	// AbsPath is the absolute path to the directory
+	// holding the module's go.mod file on the local file system.
 	AbsPath() string
 
 	// Latest version tagged locally.
@@ -50,6 +54,10 @@ type LaModule interface {
 
 	// GetReplacements returns a list of replacements.
 	GetReplacements() []string
+
+	// IsUnPinned is true if this module's dependency on the
+	// argument is replaced by a local filesystem path.
+	IsUnPinned(LaModule) bool
 }
 
 // VersionMap holds the versions associated with modules.
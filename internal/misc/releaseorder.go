@@ -0,0 +1,69 @@
+package misc
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// ReleaseOrder returns the targets sorted such that each module
+// follows every in-repo module it depends on, directly or
+// transitively.  Releasing in this order means that a module's
+// dependencies are tagged before the module itself.
+// It's an error if the dependencies of a target form a cycle.
+func (s LesModules) ReleaseOrder(targets LesModules) (LesModules, error) {
+	isTarget := make(map[ModuleShortName]bool)
+	for _, m := range targets {
+		isTarget[m.ShortName()] = true
+	}
+	state := make(map[ModuleShortName]int)
+	var path []ModuleShortName
+	var result LesModules
+	var visit func(m LaModule) error
+	visit = func(m LaModule) error {
+		switch state[m.ShortName()] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf(
+				"dependency cycle: %s", cycle(path, m.ShortName()))
+		}
+		state[m.ShortName()] = visiting
+		path = append(path, m.ShortName())
+		for _, dep := range s.InternalDeps(m) {
+			if err := visit(dep.M); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[m.ShortName()] = visited
+		if isTarget[m.ShortName()] {
+			result = append(result, m)
+		}
+		return nil
+	}
+	for _, m := range targets {
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// cycle renders the tail of the path starting at n, closing the loop.
+func cycle(path []ModuleShortName, n ModuleShortName) string {
+	i := len(path) - 1
+	for i > 0 && path[i] != n {
+		i--
+	}
+	var names []string
+	for _, p := range path[i:] {
+		names = append(names, string(p))
+	}
+	return strings.Join(append(names, string(n)), " -> ")
+}
@@ -0,0 +1,82 @@
+package misc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/testutil"
+)
+
+func TestReleaseOrder(t *testing.T) {
+	var testCases = map[string]struct {
+		deps     map[string][]string
+		targets  []string
+		expected string
+		errMsg   string
+	}{
+		"independent": {
+			targets:  []string{"api", "kyaml"},
+			expected: "api kyaml",
+		},
+		"chain": {
+			deps: map[string][]string{
+				"kustomize": {"api"},
+				"api":       {"kyaml"},
+			},
+			targets:  []string{"kustomize", "api", "kyaml"},
+			expected: "kyaml api kustomize",
+		},
+		"transitive through non-target": {
+			deps: map[string][]string{
+				"kustomize": {"api"},
+				"api":       {"kyaml"},
+			},
+			targets:  []string{"kustomize", "kyaml"},
+			expected: "kyaml kustomize",
+		},
+		"diamond": {
+			deps: map[string][]string{
+				"kustomize":  {"api", "cmd/config"},
+				"api":        {"kyaml"},
+				"cmd/config": {"kyaml"},
+			},
+			targets:  []string{"kustomize", "cmd/config", "api", "kyaml"},
+			expected: "kyaml api cmd/config kustomize",
+		},
+		"cycle": {
+			deps: map[string][]string{
+				"kustomize": {"api"},
+				"api":       {"kyaml"},
+				"kyaml":     {"api"},
+			},
+			targets: []string{"kustomize"},
+			errMsg:  "dependency cycle: api -> kyaml -> api",
+		},
+	}
+	names := []string{"api", "cmd/config", "kustomize", "kyaml"}
+	for n, tc := range testCases {
+		all := testutil.NewModules(t, names, tc.deps)
+		var targets misc.LesModules
+		for _, name := range tc.targets {
+			targets = append(targets, all.Find(misc.ModuleShortName(name)))
+		}
+		actual, err := all.ReleaseOrder(targets)
+		if err != nil {
+			if tc.errMsg != err.Error() {
+				t.Errorf("%s: expected err %q, got %q", n, tc.errMsg, err)
+			}
+			continue
+		}
+		if tc.errMsg != "" {
+			t.Errorf("%s: no error, but expected err %q", n, tc.errMsg)
+		}
+		var got []string
+		for _, m := range actual {
+			got = append(got, string(m.ShortName()))
+		}
+		if strings.Join(got, " ") != tc.expected {
+			t.Errorf("%s: expected %q, got %q", n, tc.expected, got)
+		}
+	}
+}
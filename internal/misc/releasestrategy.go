@@ -0,0 +1,29 @@
+package misc
+
+import "fmt"
+
+// ReleaseStrategy selects the git steps taken to release a module.
+type ReleaseStrategy string
+
+const (
+	// BranchPerMinor creates (or reuses) a release branch per
+	// minor version, e.g. release-kyaml-v0.10, and tags that.
+	BranchPerMinor = ReleaseStrategy("branch-per-minor")
+	// BranchPerMajor creates (or reuses) a release branch per
+	// major version, e.g. release-kyaml-v1, and tags that.
+	BranchPerMajor = ReleaseStrategy("branch-per-major")
+	// Trunk tags the remote's main branch directly,
+	// never creating release branches.
+	Trunk = ReleaseStrategy("trunk")
+)
+
+// ParseReleaseStrategy converts a string to a ReleaseStrategy.
+func ParseReleaseStrategy(raw string) (ReleaseStrategy, error) {
+	switch s := ReleaseStrategy(raw); s {
+	case BranchPerMinor, BranchPerMajor, Trunk:
+		return s, nil
+	}
+	return BranchPerMinor, fmt.Errorf(
+		"unknown release strategy %q; specify %q, %q or %q",
+		raw, BranchPerMinor, BranchPerMajor, Trunk)
+}
@@ -50,19 +50,31 @@ func (m *Module) ShortName() misc.ModuleShortName {
 }
 
 func (m *Module) ImportPath() string {
-	return filepath.Join(m.repo.RepoPath(), string(m.ShortName()))
+	return m.mf.Module.Mod.Path
+}
+
+func (m *Module) PathMajor() int {
+	return PathMajor(m.ImportPath())
 }
 
 func (m *Module) AbsPath() string {
+	if m.shortName == misc.ModuleAtTop {
+		return m.repo.AbsPath()
+	}
 	return filepath.Join(m.repo.AbsPath(), string(m.ShortName()))
 }
 
+// DependsOn reports the required version of the target, which
+// may be a pseudo-version.  Loading the repository checks that
+// the versions of in-repo modules parse.
 func (m *Module) DependsOn(target misc.LaModule) (bool, semver.SemVer) {
 	for _, r := range m.mf.Require {
 		if r.Mod.Path == target.ImportPath() {
 			v, err := semver.Parse(r.Mod.Version)
 			if err != nil {
-				panic(err)
+				panic(fmt.Errorf(
+					"module %q requires %q at %q; %v",
+					m.ShortName(), target.ImportPath(), r.Mod.Version, err))
 			}
 			return true, v
 		}
@@ -77,3 +89,13 @@ func (m *Module) GetReplacements() (result []string) {
 	}
 	return
 }
+
+func (m *Module) IsUnPinned(target misc.LaModule) bool {
+	for _, r := range m.mf.Replace {
+		// A replacement without a version is a filesystem path.
+		if r.Old.Path == target.ImportPath() && r.New.Version == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -1 +1,103 @@
 package mod_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/mod"
+	"github.com/monopole/gorepomod/internal/semver"
+	"github.com/monopole/gorepomod/internal/testutil"
+)
+
+func TestCheckPathMajor(t *testing.T) {
+	var testCases = map[string]struct {
+		importPath string
+		version    string
+		major      int
+		errMsg     string
+	}{
+		"v0": {
+			importPath: "example.com/multi/kyaml",
+			version:    "v0.10.3",
+		},
+		"v1": {
+			importPath: "example.com/multi/kyaml",
+			version:    "v1.2.0",
+		},
+		"v2NoSuffix": {
+			importPath: "example.com/multi/kyaml",
+			version:    "v2.0.0",
+			errMsg:     "needs module path example.com/multi/kyaml/v2",
+		},
+		"v2": {
+			importPath: "example.com/multi/kyaml/v2",
+			version:    "v2.1.0",
+			major:      2,
+		},
+		"v3WithV2Suffix": {
+			importPath: "example.com/multi/kyaml/v2",
+			version:    "v3.0.0",
+			major:      2,
+			errMsg:     "requires v2 versions, not v3.0.0",
+		},
+		"v1WithV2Suffix": {
+			importPath: "example.com/multi/kyaml/v2",
+			version:    "v1.9.0",
+			major:      2,
+			errMsg:     "requires v2 versions",
+		},
+		"gopkg": {
+			importPath: "gopkg.in/yaml.v3",
+			version:    "v3.0.1",
+			major:      3,
+		},
+	}
+	for n, tc := range testCases {
+		if actual := mod.PathMajor(tc.importPath); actual != tc.major {
+			t.Errorf("%s: expected major %d, got %d", n, tc.major, actual)
+		}
+		v, err := semver.Parse(tc.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mod.CheckPathMajor(tc.importPath, v)
+		if tc.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("%s: expected error containing %q, got %v", n, tc.errMsg, err)
+		}
+	}
+}
+
+func TestDependsOn(t *testing.T) {
+	kyaml := testutil.NewModule(
+		t, "kyaml", semver.Zero(), "module gh.com/micheal/kyaml\n")
+	var testCases = map[string]struct {
+		require  string
+		expected string
+	}{
+		"release": {
+			require:  "v0.3.0",
+			expected: "v0.3.0",
+		},
+		"pseudoVersion": {
+			require:  "v0.3.1-0.20200101000000-abcdef123456",
+			expected: "v0.3.1-0.20200101000000-abcdef123456",
+		},
+	}
+	for n, tc := range testCases {
+		api := testutil.NewModule(t, "api", semver.Zero(),
+			"module gh.com/micheal/api\nrequire gh.com/micheal/kyaml "+tc.require+"\n")
+		yes, v := api.DependsOn(kyaml)
+		if !yes || v.String() != tc.expected {
+			t.Errorf("%s: expected %s, got %v %s", n, tc.expected, yes, v)
+		}
+	}
+	if yes, _ := kyaml.DependsOn(kyaml); yes {
+		t.Errorf("expected no dependency")
+	}
+}
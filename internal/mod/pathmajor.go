@@ -0,0 +1,47 @@
+package mod
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/semver"
+	"golang.org/x/mod/module"
+)
+
+// PathMajor returns the major version required by the module
+// path's suffix, e.g. 2 for example.com/foo/v2, or 0 if the path
+// has no suffix, in which case only v0 and v1 versions are allowed.
+// See https://go.dev/ref/mod#major-version-suffixes
+func PathMajor(importPath string) int {
+	_, pathMajor, ok := module.SplitPathVersion(importPath)
+	if !ok || pathMajor == "" {
+		return 0
+	}
+	// Either "/v2" or, for gopkg.in, ".v2".
+	n, err := strconv.Atoi(strings.TrimLeft(pathMajor[1:], "v"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// CheckPathMajor returns an error if the Go toolchain wouldn't
+// accept the version as a version of the module with the path,
+// because the version's major version and the path's major
+// version suffix disagree.
+func CheckPathMajor(importPath string, v semver.SemVer) error {
+	want := PathMajor(importPath)
+	switch {
+	case want == 0 && v.Major() > 1:
+		return fmt.Errorf(
+			"version %s needs module path %s/v%d, but the path has no "+
+				"major version suffix, so Go would see %s+incompatible",
+			v, importPath, v.Major(), v)
+	case want != 0 && v.Major() != want:
+		return fmt.Errorf(
+			"module path %s requires v%d versions, not %s",
+			importPath, want, v)
+	}
+	return nil
+}
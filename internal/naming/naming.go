@@ -0,0 +1,243 @@
+package naming
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/mod"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+const (
+	// DefaultBranch names release branches like
+	// release-kyaml-v0.10 (or release-v0.10 for the top module).
+	DefaultBranch = `release-{{if .Module}}{{.Module}}-{{end}}v{{.Major}}.{{.Minor}}`
+	// DefaultBranchPerMajor names release branches like
+	// release-kyaml-v1, for the branch-per-major strategy.
+	DefaultBranchPerMajor = `release-{{if .Module}}{{.Module}}-{{end}}v{{.Major}}`
+	// DefaultTag names tags like kyaml/v0.10.3
+	// (or v0.10.3 for the top module), as Go requires.
+	DefaultTag = `{{if .Module}}{{.Module}}/{{end}}{{.Version}}`
+)
+
+// A version unlikely to appear in a template by accident,
+// used to find where the version lands in a rendered tag.
+var sampleVersion, _ = semver.Parse("v97.98.99-rc.1")
+
+// Data is what templates see.
+type Data struct {
+	// Module is the module's short name, e.g. "kyaml", or
+	// empty for the module at the repository root.  For a module
+	// in a major version subdirectory, e.g. kyaml/v2, it's the
+	// short name without the major version, e.g. "kyaml", as
+	// in the module's tags.
+	Module string
+	// ImportPath is the module path, e.g. sigs.k8s.io/kustomize/kyaml
+	ImportPath string
+	// Version is the full version, e.g. v1.2.3-rc.1
+	Version string
+	Major   int
+	Minor   int
+	Patch   int
+	// Pre is the pre-release, e.g. rc.1, if any.
+	Pre string
+}
+
+func newData(
+	n misc.ModuleShortName, importPath string, v semver.SemVer) Data {
+	d := Data{
+		ImportPath: importPath,
+		Version:    v.String(),
+		Major:      v.Major(),
+		Minor:      v.Minor(),
+		Patch:      v.Patch(),
+		Pre:        v.PreRelease(),
+	}
+	d.Module = tagDir(n, importPath)
+	return d
+}
+
+// tagDir is the directory prefixing the module's tags, the
+// module's directory relative to the repository root, without
+// any major version subdirectory, e.g. "kyaml" for a module
+// in kyaml/v2 with path .../kyaml/v2, or "" at the top.
+// See https://golang.org/ref/mod#vcs-version
+func tagDir(n misc.ModuleShortName, importPath string) string {
+	if n == misc.ModuleAtTop {
+		return ""
+	}
+	dir := string(n)
+	if major := mod.PathMajor(importPath); major > 1 {
+		suffix := fmt.Sprintf("v%d", major)
+		if dir == suffix {
+			return ""
+		}
+		dir = strings.TrimSuffix(dir, "/"+suffix)
+	}
+	return dir
+}
+
+// Scheme names release branches and tags.
+type Scheme struct {
+	branch *template.Template
+	tag    *template.Template
+}
+
+// New returns a Scheme using the given text/template
+// sources.  Empty sources select the defaults.
+func New(branch, tag string) (*Scheme, error) {
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	if tag == "" {
+		tag = DefaultTag
+	}
+	var err error
+	s := &Scheme{}
+	s.branch, err = template.New("branch").Option("missingkey=error").Parse(branch)
+	if err != nil {
+		return nil, fmt.Errorf("bad branch template: %v", err)
+	}
+	s.tag, err = template.New("tag").Option("missingkey=error").Parse(tag)
+	if err != nil {
+		return nil, fmt.Errorf("bad tag template: %v", err)
+	}
+	return s, nil
+}
+
+func render(t *template.Template, d Data) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, d); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Branch returns the release branch for the module at the version.
+func (s *Scheme) Branch(
+	n misc.ModuleShortName, importPath string,
+	v semver.SemVer) (string, error) {
+	b, err := render(s.branch, newData(n, importPath, v))
+	if err != nil {
+		return "", err
+	}
+	if b == "" || strings.ContainsAny(b, " \t\n~^:?*[\\") ||
+		strings.Contains(b, "..") {
+		return "", fmt.Errorf(
+			"branch template yields bad branch name %q for %s", b, n)
+	}
+	return b, nil
+}
+
+// Tag returns the release tag for the module at the version.
+// It's an error if the tag isn't the one the Go toolchain
+// looks for, i.e. the version prefixed by the path from
+// the repository root to the module's directory.
+func (s *Scheme) Tag(
+	n misc.ModuleShortName, importPath string,
+	v semver.SemVer) (string, error) {
+	t, err := render(s.tag, newData(n, importPath, v))
+	if err != nil {
+		return "", err
+	}
+	if want := goTag(n, importPath, v); t != want {
+		return "", fmt.Errorf(
+			"tag template yields %q for %s, but Go requires %q", t, n, want)
+	}
+	return t, nil
+}
+
+// goTag is the tag Go requires for the module's version.
+// See https://golang.org/ref/mod#vcs-version
+func goTag(n misc.ModuleShortName, importPath string, v semver.SemVer) string {
+	if dir := tagDir(n, importPath); dir != "" {
+		return dir + "/" + v.String()
+	}
+	return v.String()
+}
+
+// Module identifies a module for a TagMatcher.
+type Module struct {
+	ShortName  misc.ModuleShortName
+	ImportPath string
+}
+
+type tagPattern struct {
+	n              misc.ModuleShortName
+	importPath     string
+	prefix, suffix string
+}
+
+// TagMatcher recognizes the tags of a set of modules.
+type TagMatcher struct {
+	// Sorted by decreasing prefix length, so that
+	// the most specific pattern is tried first.
+	patterns []tagPattern
+}
+
+// Matcher returns a TagMatcher recognizing the given modules' tags.
+// It's an error if the scheme yields bad tags for any module.
+func (s *Scheme) Matcher(modules []Module) (*TagMatcher, error) {
+	tm := &TagMatcher{}
+	for _, m := range modules {
+		t, err := s.Tag(m.ShortName, m.ImportPath, sampleVersion)
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index(t, sampleVersion.String())
+		if i < 0 {
+			return nil, fmt.Errorf(
+				"tag template must include the version; got %q", t)
+		}
+		tm.patterns = append(tm.patterns, tagPattern{
+			n:          m.ShortName,
+			importPath: m.ImportPath,
+			prefix:     t[:i],
+			suffix:     t[i+len(sampleVersion.String()):],
+		})
+	}
+	sort.SliceStable(tm.patterns, func(i, j int) bool {
+		return len(tm.patterns[i].prefix) > len(tm.patterns[j].prefix)
+	})
+	return tm, nil
+}
+
+// Match returns the module and version named by the tag,
+// or an error if the tag isn't the tag of a known module.
+//
+// The modules in a directory and in its major version
+// subdirectories, e.g. kyaml and kyaml/v2, share tags, so
+// a tag goes to the module whose path's major version suffix
+// agrees with the version, e.g. kyaml/v2.1.0 to kyaml/v2.
+// Failing that, it goes to the module with the longest prefix.
+func (tm *TagMatcher) Match(
+	tag string) (misc.ModuleShortName, semver.SemVer, error) {
+	var fallback *tagPattern
+	var fallbackV semver.SemVer
+	for i, p := range tm.patterns {
+		if !strings.HasPrefix(tag, p.prefix) ||
+			!strings.HasSuffix(tag, p.suffix) ||
+			len(tag) < len(p.prefix)+len(p.suffix) {
+			continue
+		}
+		v, err := semver.Parse(tag[len(p.prefix) : len(tag)-len(p.suffix)])
+		if err != nil {
+			continue
+		}
+		if mod.CheckPathMajor(p.importPath, v) == nil {
+			return p.n, v, nil
+		}
+		if fallback == nil {
+			fallback, fallbackV = &tm.patterns[i], v
+		}
+	}
+	if fallback != nil {
+		return fallback.n, fallbackV, nil
+	}
+	return misc.ModuleUnknown, semver.Zero(),
+		fmt.Errorf("tag %q doesn't name a known module version", tag)
+}
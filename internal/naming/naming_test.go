@@ -0,0 +1,184 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+func mustParse(t *testing.T, raw string) semver.SemVer {
+	v, err := semver.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestBranchAndTag(t *testing.T) {
+	var testCases = map[string]struct {
+		branchTmpl string
+		tagTmpl    string
+		module     misc.ModuleShortName
+		version    string
+		branch     string
+		tag        string
+		errMsg     string
+	}{
+		"defaults": {
+			module:  "kyaml",
+			version: "v0.10.3",
+			branch:  "release-kyaml-v0.10",
+			tag:     "kyaml/v0.10.3",
+		},
+		"defaultsAtTop": {
+			module:  misc.ModuleAtTop,
+			version: "v1.2.3-rc.1",
+			branch:  "release-v1.2",
+			tag:     "v1.2.3-rc.1",
+		},
+		"customBranch": {
+			branchTmpl: "rel/{{.Module}}/{{.Major}}.x",
+			module:     "api/krusty",
+			version:    "v2.0.1",
+			branch:     "rel/api/krusty/2.x",
+			tag:        "api/krusty/v2.0.1",
+		},
+		"importPathInBranch": {
+			branchTmpl: "{{.ImportPath}}-{{.Major}}",
+			module:     "kyaml",
+			version:    "v1.0.0",
+			branch:     "sigs.k8s.io/kustomize/kyaml-1",
+			tag:        "kyaml/v1.0.0",
+		},
+		"majorSubdirectory": {
+			module:  "kyaml/v2",
+			version: "v2.1.0",
+			branch:  "release-kyaml-v2.1",
+			tag:     "kyaml/v2.1.0",
+		},
+		"topMajorSubdirectory": {
+			module:  "v3",
+			version: "v3.0.1",
+			branch:  "release-v3.0",
+			tag:     "v3.0.1",
+		},
+		"badBranch": {
+			branchTmpl: "release {{.Module}}",
+			module:     "kyaml",
+			version:    "v1.0.0",
+			errMsg:     `bad branch name "release kyaml"`,
+		},
+		"equivalentTag": {
+			tagTmpl: "{{.Module}}/v{{.Major}}.{{.Minor}}.{{.Patch}}" +
+				"{{if .Pre}}-{{.Pre}}{{end}}",
+			module:  "kyaml",
+			version: "v1.2.3-rc.2",
+			branch:  "release-kyaml-v1.2",
+			tag:     "kyaml/v1.2.3-rc.2",
+		},
+		"tagNotForGo": {
+			tagTmpl: "{{.Module}}-{{.Version}}",
+			module:  "kyaml",
+			version: "v1.2.3",
+			errMsg:  `yields "kyaml-v1.2.3" for kyaml, but Go requires "kyaml/v1.2.3"`,
+		},
+	}
+	for n, tc := range testCases {
+		s, err := New(tc.branchTmpl, tc.tagTmpl)
+		if err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+		v := mustParse(t, tc.version)
+		ip := "sigs.k8s.io/kustomize/" + string(tc.module)
+		branch, err := s.Branch(tc.module, ip, v)
+		if err == nil {
+			var tag string
+			tag, err = s.Tag(tc.module, ip, v)
+			if err == nil && (branch != tc.branch || tag != tc.tag) {
+				t.Errorf("%s: expected %s %s, got %s %s",
+					n, tc.branch, tc.tag, branch, tag)
+			}
+		}
+		if tc.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("%s: expected error containing %q, got %v",
+				n, tc.errMsg, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	s, err := New("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := s.Matcher([]Module{
+		{ShortName: misc.ModuleAtTop, ImportPath: "gh.com/micheal"},
+		{ShortName: "api", ImportPath: "gh.com/micheal/api"},
+		{ShortName: "api/krusty", ImportPath: "gh.com/micheal/api/krusty"},
+		{ShortName: "kyaml", ImportPath: "gh.com/micheal/kyaml"},
+		{ShortName: "kyaml/v2", ImportPath: "gh.com/micheal/kyaml/v2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var testCases = map[string]struct {
+		tag     string
+		module  misc.ModuleShortName
+		version string
+	}{
+		"top": {
+			tag:     "v1.0.0",
+			module:  misc.ModuleAtTop,
+			version: "v1.0.0",
+		},
+		"sub": {
+			tag:     "api/v0.3.1-rc.1",
+			module:  "api",
+			version: "v0.3.1-rc.1",
+		},
+		"nested": {
+			tag:     "api/krusty/v2.0.0",
+			module:  "api/krusty",
+			version: "v2.0.0",
+		},
+		"majorOne": {
+			tag:     "kyaml/v1.3.0",
+			module:  "kyaml",
+			version: "v1.3.0",
+		},
+		"majorSubdirectory": {
+			tag:     "kyaml/v2.0.0",
+			module:  "kyaml/v2",
+			version: "v2.0.0",
+		},
+		"unknownModule": {
+			tag: "cmd/v1.0.0",
+		},
+		"notAVersion": {
+			tag: "api/latest",
+		},
+	}
+	for n, tc := range testCases {
+		m, v, err := tm.Match(tc.tag)
+		if tc.module == "" {
+			if err == nil {
+				t.Errorf("%s: expected no match, got %s %s", n, m, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", n, err)
+			continue
+		}
+		if m != tc.module || !v.Equals(mustParse(t, tc.version)) {
+			t.Errorf("%s: expected %s %s, got %s %s",
+				n, tc.module, tc.version, m, v)
+		}
+	}
+}
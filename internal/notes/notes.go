@@ -0,0 +1,200 @@
+// Package notes makes release notes from commit messages.
+//
+// Commits are grouped by their conventional commit type,
+// e.g. "feat" in "feat(kyaml): add Walk", see
+// https://www.conventionalcommits.org, or failing that, by
+// the pull request labels named in the message, on a line
+// like "Labels: kind/bug, area/kyaml".
+package notes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Commit is a commit to describe.
+type Commit struct {
+	Hash    string
+	Subject string
+	Body    string
+}
+
+// Group is a section of release notes.
+type Group string
+
+const (
+	Breaking Group = "Breaking changes"
+	Features Group = "Features"
+	Fixes    Group = "Bug fixes"
+	Perf     Group = "Performance"
+	Docs     Group = "Documentation"
+	Other    Group = "Other changes"
+)
+
+// Groups are in the order they appear in notes.
+var Groups = []Group{Breaking, Features, Fixes, Perf, Docs, Other}
+
+// groupOf maps conventional commit types and
+// pull request labels (sans any "kind/" prefix) to groups.
+var groupOf = map[string]Group{
+	"breaking":      Breaking,
+	"feat":          Features,
+	"feature":       Features,
+	"enhancement":   Features,
+	"fix":           Fixes,
+	"bug":           Fixes,
+	"bugfix":        Fixes,
+	"perf":          Perf,
+	"performance":   Perf,
+	"docs":          Docs,
+	"doc":           Docs,
+	"documentation": Docs,
+}
+
+var (
+	// E.g. "feat(kyaml)!: drop Walk"
+	conventional = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+	// E.g. "Labels: kind/bug, area/kyaml"
+	labels = regexp.MustCompile(`(?im)^labels?:\s*(.+)$`)
+	// E.g. "BREAKING CHANGE: Walk is gone"
+	breaking = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+)
+
+// Entry describes one commit.
+type Entry struct {
+	Hash  string
+	Scope string
+	Text  string
+}
+
+func (e Entry) String() string {
+	h := e.Hash
+	if len(h) > 7 {
+		h = h[:7]
+	}
+	if e.Scope != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Scope, e.Text, h)
+	}
+	return fmt.Sprintf("%s (%s)", e.Text, h)
+}
+
+// Notes are release notes.
+type Notes struct {
+	groups map[Group][]Entry
+}
+
+// New groups the commits, which are expected newest first.
+func New(commits []Commit) *Notes {
+	n := &Notes{groups: make(map[Group][]Entry)}
+	for _, c := range commits {
+		g, e := classify(c)
+		n.groups[g] = append(n.groups[g], e)
+	}
+	return n
+}
+
+func classify(c Commit) (Group, Entry) {
+	e := Entry{Hash: c.Hash, Text: strings.TrimSpace(c.Subject)}
+	g := Other
+	if m := conventional.FindStringSubmatch(e.Text); m != nil {
+		if cg, ok := groupOf[strings.ToLower(m[1])]; ok {
+			g = cg
+			e.Scope = m[2]
+			e.Text = m[4]
+		}
+		if m[3] == "!" {
+			g = Breaking
+			e.Scope = m[2]
+			e.Text = m[4]
+		}
+	}
+	if g == Other {
+		g = labelGroup(c.Body)
+	}
+	if breaking.MatchString(c.Body) {
+		g = Breaking
+	}
+	return g, e
+}
+
+// labelGroup returns the group of the first recognized
+// label in the message, or Other if there's none.
+func labelGroup(body string) Group {
+	for _, m := range labels.FindAllStringSubmatch(body, -1) {
+		for _, l := range strings.FieldsFunc(m[1], func(r rune) bool {
+			return r == ',' || r == ' '
+		}) {
+			l = strings.TrimPrefix(strings.ToLower(l), "kind/")
+			if g, ok := groupOf[l]; ok {
+				return g
+			}
+		}
+	}
+	return Other
+}
+
+// IsEmpty is true if there are no commits to describe.
+func (n *Notes) IsEmpty() bool {
+	return len(n.groups) == 0
+}
+
+// Entries returns the entries in the group.
+func (n *Notes) Entries(g Group) []Entry {
+	return n.groups[g]
+}
+
+// Text renders the notes as plain text, e.g. for a tag message.
+func (n *Notes) Text() string {
+	var b strings.Builder
+	for _, g := range Groups {
+		if len(n.groups[g]) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(string(g) + ":\n")
+		for _, e := range n.groups[g] {
+			b.WriteString(" - " + e.String() + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Markdown renders the notes as a changelog section
+// with the given title, e.g. "kyaml/v1.2.3".
+func (n *Notes) Markdown(title string) string {
+	var b strings.Builder
+	b.WriteString("## " + title + "\n")
+	if n.IsEmpty() {
+		b.WriteString("\nNo changes.\n")
+	}
+	for _, g := range Groups {
+		if len(n.groups[g]) == 0 {
+			continue
+		}
+		b.WriteString("\n### " + string(g) + "\n\n")
+		for _, e := range n.groups[g] {
+			b.WriteString("- " + e.String() + "\n")
+		}
+	}
+	return b.String()
+}
+
+// changelogTitle starts a changelog.
+const changelogTitle = "# Changelog\n"
+
+// AddToChangelog returns the changelog with the section
+// inserted before the others, below any title.
+func AddToChangelog(changelog, section string) string {
+	if !strings.HasPrefix(changelog, changelogTitle) {
+		changelog = changelogTitle + "\n" + changelog
+	}
+	rest := strings.TrimLeft(changelog[len(changelogTitle):], "\n")
+	result := changelogTitle + "\n" + section
+	if rest != "" {
+		result += "\n" + rest
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package notes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	var testCases = map[string]struct {
+		commit        Commit
+		expectedGroup Group
+		expectedEntry string
+	}{
+		"plain": {
+			commit:        Commit{Hash: "0123456789", Subject: "Tidy up"},
+			expectedGroup: Other,
+			expectedEntry: "Tidy up (0123456)",
+		},
+		"feature": {
+			commit:        Commit{Hash: "abc", Subject: "feat: add Walk"},
+			expectedGroup: Features,
+			expectedEntry: "add Walk (abc)",
+		},
+		"scopedFix": {
+			commit:        Commit{Hash: "abc", Subject: "fix(kyaml): nil map"},
+			expectedGroup: Fixes,
+			expectedEntry: "kyaml: nil map (abc)",
+		},
+		"bang": {
+			commit:        Commit{Hash: "abc", Subject: "refactor!: drop Walk"},
+			expectedGroup: Breaking,
+			expectedEntry: "drop Walk (abc)",
+		},
+		"footer": {
+			commit: Commit{
+				Hash:    "abc",
+				Subject: "feat: new Walk",
+				Body:    "Details.\n\nBREAKING CHANGE: Walk takes a context",
+			},
+			expectedGroup: Breaking,
+			expectedEntry: "new Walk (abc)",
+		},
+		"unknownType": {
+			commit:        Commit{Hash: "abc", Subject: "chore: bump deps"},
+			expectedGroup: Other,
+			expectedEntry: "chore: bump deps (abc)",
+		},
+		"label": {
+			commit: Commit{
+				Hash:    "abc",
+				Subject: "Merge the Walk fix (#123)",
+				Body:    "Labels: area/kyaml, kind/bug",
+			},
+			expectedGroup: Fixes,
+			expectedEntry: "Merge the Walk fix (#123) (abc)",
+		},
+		"typeBeatsLabel": {
+			commit: Commit{
+				Hash:    "abc",
+				Subject: "docs: explain Walk",
+				Body:    "label: enhancement",
+			},
+			expectedGroup: Docs,
+			expectedEntry: "explain Walk (abc)",
+		},
+	}
+	for n, tc := range testCases {
+		g, e := classify(tc.commit)
+		if g != tc.expectedGroup {
+			t.Errorf("%s: expected group %q, got %q", n, tc.expectedGroup, g)
+		}
+		if e.String() != tc.expectedEntry {
+			t.Errorf("%s: expected entry %q, got %q", n, tc.expectedEntry, e)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	n := New([]Commit{
+		{Hash: "a1", Subject: "fix: nil map"},
+		{Hash: "a2", Subject: "Tidy up"},
+		{Hash: "a3", Subject: "feat(walk): add depth"},
+		{Hash: "a4", Subject: "fix: typo"},
+	})
+	if n.IsEmpty() {
+		t.Fatalf("expected notes")
+	}
+	expected := []Entry{{Hash: "a1", Text: "nil map"}, {Hash: "a4", Text: "typo"}}
+	if actual := n.Entries(Fixes); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	expectedText := `Features:
+ - walk: add depth (a3)
+
+Bug fixes:
+ - nil map (a1)
+ - typo (a4)
+
+Other changes:
+ - Tidy up (a2)
+`
+	if actual := n.Text(); actual != expectedText {
+		t.Errorf("expected text\n%s\ngot\n%s", expectedText, actual)
+	}
+	expectedMd := `## kyaml/v1.2.3
+
+### Features
+
+- walk: add depth (a3)
+
+### Bug fixes
+
+- nil map (a1)
+- typo (a4)
+
+### Other changes
+
+- Tidy up (a2)
+`
+	if actual := n.Markdown("kyaml/v1.2.3"); actual != expectedMd {
+		t.Errorf("expected markdown\n%s\ngot\n%s", expectedMd, actual)
+	}
+	if actual := New(nil).Markdown("v1.0.1"); actual != "## v1.0.1\n\nNo changes.\n" {
+		t.Errorf("unexpected empty markdown %q", actual)
+	}
+}
+
+func TestAddToChangelog(t *testing.T) {
+	section := "## v1.1.0\n\n- new\n"
+	var testCases = map[string]struct {
+		changelog string
+		expected  string
+	}{
+		"new": {
+			expected: "# Changelog\n\n## v1.1.0\n\n- new\n",
+		},
+		"existing": {
+			changelog: "# Changelog\n\n## v1.0.0\n\n- old\n",
+			expected:  "# Changelog\n\n## v1.1.0\n\n- new\n\n## v1.0.0\n\n- old\n",
+		},
+		"untitled": {
+			changelog: "## v1.0.0\n\n- old\n",
+			expected:  "# Changelog\n\n## v1.1.0\n\n- new\n\n## v1.0.0\n\n- old\n",
+		},
+	}
+	for n, tc := range testCases {
+		if actual := AddToChangelog(tc.changelog, section); actual != tc.expected {
+			t.Errorf("%s: expected\n%q\ngot\n%q", n, tc.expected, actual)
+		}
+	}
+}
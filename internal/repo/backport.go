@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/mod"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+// Backport makes a patch release on an older release line, e.g.
+// v1.2.8 after main has moved on to v1.3 work.
+//
+// It checks out the line's release branch (creating it at the
+// line's latest tag if need be), cherry-picks the commits onto
+// it, runs the preflight checks, pushes it, and tags and pushes
+// the next patch version.  Main is never merged into the release
+// branch.  Like a release, the backport is journaled, so that
+// it can be resumed or aborted if a step fails.
+//
+// If a commit doesn't apply cleanly, the cherry-pick is aborted,
+// and the originally checked out branch is restored.
+//
+// Only release branches per minor version hold a single
+// line; a branch per major version may hold newer work.
+func (mgr *Manager) Backport(
+	target misc.LaModule, line semver.SemVer,
+	commits []string, doIt bool) error {
+	if mgr.strategy != misc.BranchPerMinor {
+		return fmt.Errorf(
+			"backport needs a release branch per minor version, "+
+				"but the release strategy is %q", mgr.strategy)
+	}
+	if mgr.cfg.NeverRelease(target.ShortName()) {
+		return fmt.Errorf(
+			"module %q is configured to never be released", target.ShortName())
+	}
+	latest, ok := mgr.versionsLocal[target.ShortName()].LatestOnLine(line)
+	if !ok {
+		return fmt.Errorf(
+			"module %q has no local tag on line %s",
+			target.ShortName(), line.BranchLabel())
+	}
+	newVersion := latest.Bump(semver.Patch)
+	if err := mod.CheckPathMajor(target.ImportPath(), newVersion); err != nil {
+		return fmt.Errorf("refusing to release %q: %v", target.ShortName(), err)
+	}
+	for _, vm := range []misc.VersionMap{mgr.versionsLocal, mgr.versionsRemote} {
+		for _, v := range vm[target.ShortName()] {
+			if v.Equals(newVersion) {
+				return fmt.Errorf(
+					"version %s of %q already exists", newVersion, target.ShortName())
+			}
+		}
+	}
+	var err error
+	j := &journal{
+		Module:   string(target.ShortName()),
+		Version:  newVersion.String(),
+		Strategy: mgr.strategy,
+		Signing:  mgr.signing(),
+		Backport: commits,
+	}
+	j.Branch, j.Tag, err = mgr.determineBranchAndTag(target, newVersion)
+	if err != nil {
+		return err
+	}
+	j.PreviousTag, err = mgr.tag(target, latest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Backporting %v to %s, stepping from %s to %s\n",
+		commits, target.ShortName(), latest, newVersion)
+
+	gr := git.NewLoud(mgr.AbsPath(), doIt)
+	if err := gr.AssureCleanWorkspace(); err != nil {
+		return err
+	}
+	original, err := gr.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if original == "HEAD" {
+		return fmt.Errorf("HEAD is detached; check out a branch first")
+	}
+	if err := mgr.startJournal(gr, j, doIt); err != nil {
+		return err
+	}
+	return mgr.runSteps(gr, j, mgr.backportSteps(gr, j))
+}
+
+// backportSteps returns the steps of the backport in the journal.
+func (mgr *Manager) backportSteps(gr *git.Runner, j *journal) []step {
+	return []step{
+		{
+			name: "fetch remote",
+			do: func() error {
+				return gr.FetchRemote(mgr.remoteName)
+			},
+		},
+		{
+			name:   "checkout release branch",
+			repeat: true,
+			do: func() error {
+				_, err := gr.CheckoutBranchFrom(
+					mgr.remoteName, j.Branch, j.PreviousTag)
+				return err
+			},
+		},
+		{
+			name: "cherry-pick commits",
+			do: func() error {
+				return gr.CherryPick(j.Backport)
+			},
+		},
+		{
+			name: stepNotes,
+			do: func() error {
+				return mgr.collectChanges(gr, j, "HEAD")
+			},
+		},
+		{
+			name: stepPreflight,
+			do: func() error {
+				return mgr.preflightStep(gr, j, "")
+			},
+		},
+		{
+			name:   stepPushBranch,
+			remote: true,
+			do: func() error {
+				return gr.PushBranchToRemote(mgr.remoteName, j.Branch)
+			},
+		},
+		{
+			name: stepTag,
+			do: func() error {
+				return gr.CreateLocalReleaseTag(
+					j.Tag, releaseMessage(j.Tag, j.Branch, j.Changes),
+					"", j.Signing)
+			},
+		},
+		{
+			name:   stepPushTag,
+			remote: true,
+			do: func() error {
+				return gr.PushTagToRemote(mgr.remoteName, j.Tag)
+			},
+		},
+		{
+			name:   "checkout original branch",
+			repeat: true,
+			do: func() error {
+				return gr.CheckoutBranch(j.OriginalBranch)
+			},
+		},
+	}
+}
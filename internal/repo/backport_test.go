@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/config"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+// newBackportRepo returns a test repo where main has moved on
+// to kyaml/v0.2.0, followed by a fix to backport onto v0.1,
+// and a change to Walk, which v0.1 doesn't have.
+func newBackportRepo(t *testing.T) (r *testRepo, fix, walk string) {
+	r = newTestRepo(t)
+	r.git("tag", "-a", "-m", "Release kyaml/v0.2.0", "kyaml/v0.2.0")
+	r.write("kyaml/fix.go", "package kyaml\n\nvar Fixed = true\n")
+	r.commit("fix: nil map")
+	fix = r.git("rev-parse", "HEAD")
+	r.write("kyaml/walk.go", "package kyaml\n\nfunc Walk(depth int) {}\n")
+	r.commit("feat: Walk takes a depth")
+	walk = r.git("rev-parse", "HEAD")
+	r.git("push", "-q", "origin", "main", "kyaml/v0.2.0")
+	return
+}
+
+func TestBackport(t *testing.T) {
+	var testCases = map[string]struct {
+		strategy  misc.ReleaseStrategy
+		walk      bool
+		preflight string
+		errMsg    string
+	}{
+		"fix": {
+			strategy: misc.BranchPerMinor,
+		},
+		"conflict": {
+			strategy: misc.BranchPerMinor,
+			walk:     true,
+			errMsg:   "conflicts",
+		},
+		"preflightFails": {
+			strategy:  misc.BranchPerMinor,
+			preflight: "test ! -f fix.go",
+			errMsg:    "preflight",
+		},
+		"branchPerMajor": {
+			strategy: misc.BranchPerMajor,
+			errMsg:   "needs a release branch per minor version",
+		},
+		"trunk": {
+			strategy: misc.Trunk,
+			errMsg:   "needs a release branch per minor version",
+		},
+	}
+	line, err := semver.ParseLine("v0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, tc := range testCases {
+		r, fix, walk := newBackportRepo(t)
+		head := r.git("rev-parse", "HEAD")
+		mgr := r.manager(tc.strategy)
+		if tc.preflight != "" {
+			mgr.cfg.Preflight = config.Preflight{Commands: []string{tc.preflight}}
+		}
+		commits := []string{fix}
+		if tc.walk {
+			commits = append(commits, walk)
+		}
+		err := mgr.Backport(mgr.FindModule("kyaml"), line, commits, true)
+		released := tc.errMsg == ""
+		if released {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("%s: expected error containing %q, got %v", n, tc.errMsg, err)
+		}
+		if r.remoteHasRef("refs/tags/kyaml/v0.1.1") != released {
+			t.Errorf("%s: expected tag pushed %v", n, released)
+		}
+		if r.hasRef("refs/heads/release-kyaml-v0.1") != released {
+			t.Errorf("%s: expected release branch %v", n, released)
+		}
+		if released {
+			// The tag has the fix, but not the newer work.
+			tree := func(p string) bool {
+				return exec.Command("git", "-C", r.dir, "cat-file", "-e",
+					"kyaml/v0.1.1:kyaml/"+p).Run() == nil
+			}
+			if !tree("fix.go") || tree("walk.go") {
+				t.Errorf("%s: expected only the fix in kyaml/v0.1.1", n)
+			}
+		}
+		if actual := r.git("rev-parse", "--abbrev-ref", "HEAD"); actual != "main" {
+			t.Errorf("%s: expected main checked out, got %s", n, actual)
+		}
+		if actual := r.git("rev-parse", "HEAD"); actual != head {
+			t.Errorf("%s: expected HEAD %s, got %s", n, head, actual)
+		}
+		if j, err := mgr.loadJournal(); err != nil || j != nil {
+			t.Errorf("%s: expected no journal, got %v %v", n, j, err)
+		}
+		r.close()
+	}
+}
+
+func TestBackportResume(t *testing.T) {
+	r, fix, _ := newBackportRepo(t)
+	defer r.close()
+	line, err := semver.ParseLine("v0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := r.manager(misc.BranchPerMinor)
+	r.refuse("refs/tags/")
+	err = mgr.Backport(mgr.FindModule("kyaml"), line, []string{fix}, true)
+	if err == nil {
+		t.Fatal("expected the backport to fail")
+	}
+	j, err := mgr.loadJournal()
+	if err != nil || j == nil {
+		t.Fatalf("expected a journal, got %v %v", j, err)
+	}
+	r.allow()
+	if err = mgr.Resume(true); err != nil {
+		t.Fatal(err)
+	}
+	if !r.remoteHasRef("refs/tags/kyaml/v0.1.1") {
+		t.Error("expected the tag on the remote")
+	}
+	if actual := r.git("rev-parse", "--abbrev-ref", "HEAD"); actual != "main" {
+		t.Errorf("expected main checked out, got %s", actual)
+	}
+	// The fix was cherry-picked once.
+	if actual := r.git(
+		"rev-list", "--count", "kyaml/v0.1.0..kyaml/v0.1.1"); actual != "1" {
+		t.Errorf("expected one commit on the line, got %s", actual)
+	}
+}
@@ -7,66 +7,105 @@ import (
 
 	"github.com/monopole/gorepomod/internal/git"
 	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/naming"
+	"github.com/monopole/gorepomod/internal/semver"
 	"github.com/monopole/gorepomod/internal/utils"
+	"github.com/monopole/gorepomod/internal/work"
 )
 
 const (
 	dotGitFileName = ".git"
-	srcHint        = "/src/"
 	goModFile      = "go.mod"
+	goSumFile      = "go.sum"
 )
 
 // DotGitData holds basic information about a local .git file
 type DotGitData struct {
-	// srcPath is the absolute path to the local Go src directory.
-	// This used to be $GOPATH/src.
-	// It's the directory containing git repository clones.
-	srcPath string
-	// The path below srcPath to a particular repository
+	// absPath is the absolute path to the local repository
 	// directory, a directory containing a .git directory.
+	absPath string
+	// The import path of the repository.
 	// Typically {repoOrg}/{repoUserName}, e.g. sigs.k8s.io/cli-utils
 	repoPath string
-}
-
-func (dg *DotGitData) SrcPath() string {
-	return dg.srcPath
+	// The directories used by a go.work file in the
+	// repository root, or nil if there's no such file.
+	workspaceUses []string
 }
 
 func (dg *DotGitData) RepoPath() string {
 	return dg.repoPath
 }
 
+// WorkspaceUses returns the directories used by the
+// repository's go.work file, if it has one.
+func (dg *DotGitData) WorkspaceUses() []string {
+	return dg.workspaceUses
+}
+
 func (dg *DotGitData) AbsPath() string {
-	return filepath.Join(dg.srcPath, dg.repoPath)
+	return dg.absPath
+}
+
+// FindRepoRoot returns the closest directory at or above the
+// given path that holds dotGit.  In a work tree added with
+// 'git worktree add', or in a submodule, dotGit is a file
+// rather than a directory.
+func FindRepoRoot(path string) (string, error) {
+	dir := filepath.Clean(path)
+	for {
+		if utils.PathExists(filepath.Join(dir, dotGitFileName)) {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf(
+				"neither %q nor any parent has a %q file", path, dotGitFileName)
+		}
+		dir = parent
+	}
 }
 
 // NewDotGitDataFromPath wants the incoming path to hold dotGit
 // E.g.
 //   ~/gopath/src/sigs.k8s.io/kustomize
-//   ~/gopath/src/github.com/monopole/gorepomod
-func NewDotGitDataFromPath(path string) (*DotGitData, error) {
-	if !utils.DirExists(filepath.Join(path, dotGitFileName)) {
+//   ~/work/gorepomod
+//
+// The repoPath argument is the repository's import path, e.g.
+// sigs.k8s.io/kustomize.  If empty, it's derived from the
+// go.mod files in the repository, or from the git remote's URL.
+func NewDotGitDataFromPath(path, repoPath string) (*DotGitData, error) {
+	if !utils.PathExists(filepath.Join(path, dotGitFileName)) {
 		return nil, fmt.Errorf(
 			"%q doesn't have a %q file", path, dotGitFileName)
 	}
-	// This is an attempt to figure out where the user has cloned
-	// their repos.  In the old days, it was an import path under
-	// $GOPATH/src.  If we cannot guess it, we may need to ask for it,
-	// or maybe proceed without knowing it.
-	index := strings.Index(path, srcHint)
-	if index < 0 {
-		return nil, fmt.Errorf(
-			"path %q doesn't contain %q", path, srcHint)
+	if repoPath == "" {
+		var err error
+		repoPath, err = inferRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return &DotGitData{
-		srcPath:  path[:index+len(srcHint)-1],
-		repoPath: path[index+len(srcHint):],
-	}, nil
+	dg := &DotGitData{
+		absPath:  path,
+		repoPath: repoPath,
+	}
+	if work.Exists(path) {
+		w, err := work.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		dg.workspaceUses = w.Uses()
+	}
+	return dg, nil
 }
 
 // It's a factory factory.
+// The remote and main branch name may be empty, in which case
+// they're determined from the repository.  The naming scheme
+// determines which tags belong to which modules.
 func (dg *DotGitData) NewRepoFactory(
-	exclusions []string) (*ManagerFactory, error) {
+	exclusions []string, remote misc.TrackedRepo,
+	mainBranch string, names *naming.Scheme) (*ManagerFactory, error) {
 	modules, err := loadProtoModules(dg.AbsPath(), exclusions)
 	if err != nil {
 		return nil, err
@@ -75,22 +114,39 @@ func (dg *DotGitData) NewRepoFactory(
 	if err != nil {
 		return nil, err
 	}
+	var known []naming.Module
+	for _, pm := range modules {
+		known = append(known, naming.Module{
+			ShortName:  pm.ShortName(dg.RepoPath()),
+			ImportPath: pm.FullPath(),
+		})
+	}
+	tm, err := names.Matcher(known)
+	if err != nil {
+		return nil, err
+	}
 
 	runner := git.NewQuiet(dg.AbsPath(), true)
-	remoteName, err := runner.DetermineRemoteToUse()
+	remoteName, err := runner.DetermineRemoteToUse(remote)
 	if err != nil {
 		return nil, err
 	}
+	if mainBranch == "" {
+		mainBranch, err = runner.DetermineMainBranch(remoteName)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Some tags might exist for modules that
 	// have been renamed or deleted; ignore those.
 	// There might be newer tags locally than remote,
 	// so report both.
-	localTags, err := runner.LoadLocalTags()
+	localTags, err := runner.LoadLocalTags(tm)
 	if err != nil {
 		return nil, err
 	}
-	remoteTags, err := runner.LoadRemoteTags(remoteName)
+	remoteTags, err := runner.LoadRemoteTags(remoteName, tm)
 	if err != nil {
 		return nil, err
 	}
@@ -99,12 +155,15 @@ func (dg *DotGitData) NewRepoFactory(
 		dg:               dg,
 		modules:          modules,
 		remoteName:       remoteName,
+		mainBranch:       mainBranch,
+		names:            names,
 		versionMapLocal:  localTags,
 		versionMapRemote: remoteTags,
 	}, nil
 }
 
 func (dg *DotGitData) checkModules(modules []*protoModule) error {
+	seen := make(map[misc.ModuleShortName]string)
 	for _, pm := range modules {
 
 		file := filepath.Join(pm.PathToGoMod(), goModFile)
@@ -117,6 +176,12 @@ func (dg *DotGitData) checkModules(modules []*protoModule) error {
 		}
 
 		shortName := pm.ShortName(dg.RepoPath())
+		if other, ok := seen[shortName]; ok {
+			return fmt.Errorf(
+				"modules %q and %q have the same short name %q",
+				other, pm.FullPath(), shortName)
+		}
+		seen[shortName] = pm.FullPath()
 		if shortName == misc.ModuleAtTop {
 			if pm.PathToGoMod() != dg.AbsPath() {
 				return fmt.Errorf("in %q, problem with top module", file)
@@ -130,5 +195,28 @@ func (dg *DotGitData) checkModules(modules []*protoModule) error {
 			}
 		}
 	}
+	return checkRequires(modules)
+}
+
+// checkRequires checks that the versions at which the
+// modules require each other parse, as releasing, pinning
+// and unpinning rely on them.
+func checkRequires(modules []*protoModule) error {
+	inRepo := make(map[string]bool)
+	for _, pm := range modules {
+		inRepo[pm.FullPath()] = true
+	}
+	for _, pm := range modules {
+		for _, r := range pm.mf.Require {
+			if !inRepo[r.Mod.Path] {
+				continue
+			}
+			if _, err := semver.Parse(r.Mod.Version); err != nil {
+				return fmt.Errorf(
+					"in %q, bad version of %q; %v",
+					filepath.Join(pm.PathToGoMod(), goModFile), r.Mod.Path, err)
+			}
+		}
+	}
 	return nil
 }
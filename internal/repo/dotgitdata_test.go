@@ -1,6 +1,100 @@
 package repo
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
 
 func TestLoadTags(t *testing.T) {
 }
+
+func TestFindRepoRoot(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// A work tree, where .git is a file.
+	root := filepath.Join(tmp, "wt")
+	deep := filepath.Join(root, "cmd", "config")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(
+		filepath.Join(root, dotGitFileName),
+		[]byte("gitdir: /somewhere/.git/worktrees/wt\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{root, deep} {
+		actual, err := FindRepoRoot(p)
+		if err != nil {
+			t.Fatalf("from %s: %v", p, err)
+		}
+		if actual != root {
+			t.Errorf("from %s: expected %s, got %s", p, root, actual)
+		}
+	}
+	if _, err := FindRepoRoot(tmp); err == nil {
+		t.Errorf("expected error from %s", tmp)
+	}
+}
+
+func TestCheckRequires(t *testing.T) {
+	var testCases = map[string]struct {
+		require  module.Version
+		expected string
+	}{
+		"release": {
+			require: module.Version{
+				Path: "gh.com/micheal/kyaml", Version: "v0.3.0"},
+		},
+		"pseudoVersion": {
+			require: module.Version{
+				Path:    "gh.com/micheal/kyaml",
+				Version: "v0.3.1-0.20200101000000-abcdef123456"},
+		},
+		"external": {
+			require: module.Version{
+				Path: "gh.com/other/kyaml", Version: "master"},
+		},
+		"malformed": {
+			require: module.Version{
+				Path: "gh.com/micheal/kyaml", Version: "v0.3"},
+			expected: `in "/src/api/go.mod", bad version of "gh.com/micheal/kyaml"`,
+		},
+	}
+	for n, tc := range testCases {
+		kyaml := &protoModule{
+			pathToGoMod: "/src/kyaml",
+			mf: &modfile.File{
+				Module: &modfile.Module{
+					Mod: module.Version{Path: "gh.com/micheal/kyaml"}},
+			},
+		}
+		api := &protoModule{
+			pathToGoMod: "/src/api",
+			mf: &modfile.File{
+				Module: &modfile.Module{
+					Mod: module.Version{Path: "gh.com/micheal/api"}},
+				Require: []*modfile.Require{{Mod: tc.require}},
+			},
+		}
+		err := checkRequires([]*protoModule{kyaml, api})
+		if tc.expected == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", n, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+			t.Errorf("%s: expected error %q, got %v", n, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/utils"
+)
+
+const (
+	// The journal's file name, in the git directory.
+	journalFileName = "gorepomod-journal.json"
+
+	// Steps with effects that rollback must know about.
+	stepPushMain   = "push main branch"
+	stepPushBranch = "push release branch"
+	stepTag        = "create local tag"
+	stepPushTag    = "push tag"
+	stepNotes      = "collect release notes"
+	stepChangelog  = "update changelog"
+	stepPreflight  = "run preflight checks"
+)
+
+// step is one step of a release.
+type step struct {
+	name string
+	// The step only checks something out, so it's
+	// taken again when resuming.
+	repeat bool
+	// The step changes the remote, so it cannot be undone.
+	remote bool
+	do     func() error
+}
+
+// journal records the progress of a release, so that if a step
+// fails, the release can be resumed, or its local effects undone.
+type journal struct {
+	Module   string               `json:"module"`
+	Version  string               `json:"version"`
+	Strategy misc.ReleaseStrategy `json:"strategy"`
+	// The release branch, if the strategy uses one.
+	Branch string `json:"branch,omitempty"`
+	Tag    string `json:"tag"`
+	// How the tag is signed, if at all.
+	Signing git.Signing `json:"signing"`
+	// The tag of the previous release, if any.
+	PreviousTag string `json:"previousTag,omitempty"`
+	// The changes since the previous release, for release notes.
+	Changes []git.LogEntry `json:"changes,omitempty"`
+	// The changelog to update, relative to the repository
+	// root, if any.
+	Changelog string `json:"changelog,omitempty"`
+	// The commits cherry-picked onto the release branch,
+	// if this is a backport rather than a release.
+	Backport []string `json:"backport,omitempty"`
+	// What was checked out when the release started.
+	OriginalBranch string `json:"originalBranch"`
+	OriginalHead   string `json:"originalHead"`
+	// The release branch existed locally when the release
+	// started, pointing at BranchHead.
+	BranchExisted bool   `json:"branchExisted"`
+	BranchHead    string `json:"branchHead,omitempty"`
+	// The main branch's head when the release started,
+	// if the release commits to the main branch.
+	MainHead string `json:"mainHead,omitempty"`
+	// The names of the completed steps, in order.
+	Done []string `json:"done"`
+
+	// Where the journal is saved.
+	path string
+	// In a dry run, nothing is saved, and nothing is undone.
+	dryRun bool
+}
+
+func (j *journal) isDone(name string) bool {
+	for _, n := range j.Done {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *journal) markDone(name string) error {
+	if !j.isDone(name) {
+		j.Done = append(j.Done, name)
+	}
+	return j.save()
+}
+
+// changedRemote is true if any completed step changed the remote.
+func (j *journal) changedRemote(steps []step) bool {
+	for _, s := range steps {
+		if s.remote && j.isDone(s.name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *journal) save() error {
+	if j.dryRun {
+		return nil
+	}
+	content, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.path, content, 0644)
+}
+
+func (j *journal) remove() error {
+	if j.dryRun {
+		return nil
+	}
+	return os.Remove(j.path)
+}
+
+func journalPath(gr *git.Runner) (string, error) {
+	dir, err := gr.GitDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, journalFileName), nil
+}
+
+// loadJournal returns the journal of the release in progress,
+// or nil if there isn't one.
+func (mgr *Manager) loadJournal() (*journal, error) {
+	p, err := journalPath(git.NewQuiet(mgr.AbsPath(), true))
+	if err != nil {
+		return nil, err
+	}
+	if !utils.PathExists(p) {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	j := &journal{path: p}
+	if err = json.Unmarshal(content, j); err != nil {
+		return nil, fmt.Errorf("bad journal %s: %v", p, err)
+	}
+	return j, nil
+}
+
+// startJournal records where the release starts from, refusing to
+// start if another release is in progress.  The journal is saved
+// only if doIt is true.
+func (mgr *Manager) startJournal(
+	gr *git.Runner, j *journal, doIt bool) (err error) {
+	old, err := mgr.loadJournal()
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		return fmt.Errorf(
+			"the release of %s %s is in progress; "+
+				"run 'gorepomod resume' or 'gorepomod abort' first",
+			old.Module, old.Version)
+	}
+	j.OriginalBranch, err = gr.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	j.OriginalHead, err = gr.Head()
+	if err != nil {
+		return err
+	}
+	j.BranchExisted = j.Branch != "" && gr.LocalBranchExists(j.Branch)
+	if j.BranchExisted {
+		j.BranchHead, err = gr.BranchHead(j.Branch)
+		if err != nil {
+			return err
+		}
+	}
+	if j.Changelog != "" {
+		j.MainHead, err = gr.BranchHead(mgr.mainBranch)
+		if err != nil {
+			return err
+		}
+	}
+	j.dryRun = !doIt
+	j.path, err = journalPath(gr)
+	if err != nil {
+		return err
+	}
+	return j.save()
+}
+
+// runSteps takes the steps not yet done, recording each in the
+// journal.  If a step fails before the remote is changed, the
+// local effects of the steps are undone.  Otherwise the journal
+// is kept, so the release can be resumed or aborted.
+func (mgr *Manager) runSteps(
+	gr *git.Runner, j *journal, steps []step) error {
+	for _, s := range steps {
+		if j.isDone(s.name) && !s.repeat {
+			continue
+		}
+		if err := s.do(); err != nil {
+			if j.dryRun {
+				return err
+			}
+			if j.changedRemote(steps) {
+				return fmt.Errorf(
+					"%v\nthe release of %s %s is partly done (%s); fix the "+
+						"problem, then run 'gorepomod resume --doIt', or run "+
+						"'gorepomod abort --doIt' to undo the local changes",
+					err, j.Module, j.Version, strings.Join(j.Done, ", "))
+			}
+			fmt.Printf("step %q failed; undoing local changes\n", s.name)
+			if rbErr := mgr.rollback(gr, j); rbErr != nil {
+				return fmt.Errorf("%v; rollback failed: %v", err, rbErr)
+			}
+			return err
+		}
+		if err := j.markDone(s.name); err != nil {
+			return err
+		}
+	}
+	return j.remove()
+}
+
+// rollback undoes the local effects of the release: it deletes the
+// local tag, returns to the original branch and HEAD, restores the
+// main branch if the release committed to it, restores or (if the
+// release created it) deletes the local release branch, then
+// deletes the journal.  Effects on the remote are left alone,
+// as is a release branch that was pushed.
+func (mgr *Manager) rollback(gr *git.Runner, j *journal) error {
+	if j.isDone(stepTag) {
+		if err := gr.DeleteLocalTag(j.Tag); err != nil {
+			return err
+		}
+	}
+	if j.OriginalBranch == "HEAD" {
+		// Detached.
+		if err := gr.CheckoutBranch(j.OriginalHead); err != nil {
+			return err
+		}
+	} else {
+		if err := gr.CheckoutBranch(j.OriginalBranch); err != nil {
+			return err
+		}
+		if err := gr.ResetHard(j.OriginalHead); err != nil {
+			return err
+		}
+	}
+	if j.MainHead != "" && j.OriginalBranch != mgr.mainBranch &&
+		!j.isDone(stepPushMain) {
+		if err := gr.SetBranch(mgr.mainBranch, j.MainHead); err != nil {
+			return err
+		}
+	}
+	if j.Branch != "" && j.Branch != j.OriginalBranch &&
+		!j.isDone(stepPushBranch) && gr.LocalBranchExists(j.Branch) {
+		if j.BranchExisted {
+			if err := gr.SetBranch(j.Branch, j.BranchHead); err != nil {
+				return err
+			}
+		} else if err := gr.DeleteLocalBranch(j.Branch); err != nil {
+			return err
+		}
+	}
+	return j.remove()
+}
+
+// Resume continues the release in progress from the step that failed.
+func (mgr *Manager) Resume(doIt bool) error {
+	j, err := mgr.loadJournal()
+	if err != nil {
+		return err
+	}
+	if j == nil {
+		return fmt.Errorf("no release is in progress")
+	}
+	j.dryRun = !doIt
+	fmt.Printf(
+		"Resuming the release of %s %s; done: %s\n",
+		j.Module, j.Version, strings.Join(j.Done, ", "))
+	gr := git.NewLoud(mgr.AbsPath(), doIt)
+	return mgr.runSteps(gr, j, mgr.steps(gr, j))
+}
+
+// steps returns the steps of the release or backport in the journal.
+func (mgr *Manager) steps(gr *git.Runner, j *journal) []step {
+	if len(j.Backport) > 0 {
+		return mgr.backportSteps(gr, j)
+	}
+	return mgr.releaseSteps(gr, j)
+}
+
+// Abort undoes the local effects of the release in progress.
+// Effects on the remote, e.g. a pushed tag, remain.
+func (mgr *Manager) Abort(doIt bool) error {
+	j, err := mgr.loadJournal()
+	if err != nil {
+		return err
+	}
+	if j == nil {
+		return fmt.Errorf("no release is in progress")
+	}
+	j.dryRun = !doIt
+	fmt.Printf("Aborting the release of %s %s\n", j.Module, j.Version)
+	gr := git.NewLoud(mgr.AbsPath(), doIt)
+	if j.isDone(stepPushMain) {
+		fmt.Printf(
+			"warning: the changelog commit remains on branch %s of remote %s\n",
+			mgr.mainBranch, mgr.remoteName)
+	}
+	if j.isDone(stepPushBranch) {
+		fmt.Printf(
+			"warning: branch %s remains on remote %s\n", j.Branch, mgr.remoteName)
+	}
+	if j.isDone(stepPushTag) {
+		fmt.Printf(
+			"warning: tag %s remains on remote %s; "+
+				"see 'gorepomod unrelease'\n", j.Tag, mgr.remoteName)
+	}
+	return mgr.rollback(gr, j)
+}
@@ -0,0 +1,294 @@
+package repo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+func TestJournalChangedRemote(t *testing.T) {
+	steps := []step{
+		{name: "fetch remote"},
+		{name: stepPushBranch, remote: true},
+		{name: stepTag},
+		{name: stepPushTag, remote: true},
+	}
+	var testCases = map[string]struct {
+		done     []string
+		expected bool
+	}{
+		"nothing": {
+			expected: false,
+		},
+		"localOnly": {
+			done:     []string{"fetch remote"},
+			expected: false,
+		},
+		"pushedBranch": {
+			done:     []string{"fetch remote", stepPushBranch},
+			expected: true,
+		},
+		"pushedTag": {
+			done:     []string{"fetch remote", stepTag, stepPushTag},
+			expected: true,
+		},
+	}
+	for n, tc := range testCases {
+		j := &journal{Done: tc.done}
+		if actual := j.changedRemote(steps); actual != tc.expected {
+			t.Errorf("%s: expected %v, got %v", n, tc.expected, actual)
+		}
+	}
+}
+
+func TestJournalSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, journalFileName)
+
+	// A dry run saves nothing.
+	j := &journal{
+		Module: "kyaml", Version: "v0.2.2", Tag: "kyaml/v0.2.2",
+		path: p, dryRun: true,
+	}
+	if err = j.markDone("fetch remote"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(p); !os.IsNotExist(err) {
+		t.Fatalf("expected no journal, got %v", err)
+	}
+
+	j.dryRun = false
+	if err = j.markDone(stepTag); err != nil {
+		t.Fatal(err)
+	}
+	// Steps are recorded once.
+	if err = j.markDone(stepTag); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &journal{}
+	if err = json.Unmarshal(content, loaded); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"fetch remote", stepTag}
+	if !reflect.DeepEqual(loaded.Done, expected) {
+		t.Errorf("expected %v, got %v", expected, loaded.Done)
+	}
+	if loaded.Tag != j.Tag || !loaded.isDone(stepTag) {
+		t.Errorf("expected %+v, got %+v", j, loaded)
+	}
+	if err = j.remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("expected journal to be removed, got %v", err)
+	}
+}
+
+func TestReleaseRollback(t *testing.T) {
+	var testCases = map[string]struct {
+		strategy misc.ReleaseStrategy
+		refuse   string
+		// Whether the release branch exists before the release.
+		branchExisted bool
+	}{
+		"branchPushRefused": {
+			strategy: misc.BranchPerMinor,
+			refuse:   "refs/heads/",
+		},
+		"existingBranchRestored": {
+			strategy:      misc.BranchPerMinor,
+			refuse:        "refs/heads/",
+			branchExisted: true,
+		},
+		"trunkTagPushRefused": {
+			strategy: misc.Trunk,
+			refuse:   "refs/tags/",
+		},
+	}
+	for n, tc := range testCases {
+		r := newTestRepo(t)
+		if tc.branchExisted {
+			r.git("branch", "release-kyaml-v0.2", "kyaml/v0.1.0")
+		}
+		head := r.git("rev-parse", "HEAD")
+		mgr := r.manager(tc.strategy)
+		r.refuse(tc.refuse)
+		err := mgr.Release(
+			mgr.FindModule("kyaml"), semver.Minor, nil, true)
+		if err == nil {
+			t.Errorf("%s: expected the release to fail", n)
+		}
+		if actual := r.git("rev-parse", "--abbrev-ref", "HEAD"); actual != "main" {
+			t.Errorf("%s: expected main checked out, got %s", n, actual)
+		}
+		if actual := r.git("rev-parse", "HEAD"); actual != head {
+			t.Errorf("%s: expected HEAD %s, got %s", n, head, actual)
+		}
+		if r.hasRef("refs/tags/kyaml/v0.2.0") {
+			t.Errorf("%s: expected no local tag", n)
+		}
+		branch := "refs/heads/release-kyaml-v0.2"
+		if tc.branchExisted {
+			if actual := r.git("rev-parse", branch); actual !=
+				r.git("rev-parse", "kyaml/v0.1.0^{commit}") {
+				t.Errorf("%s: expected release branch restored, got %s", n, actual)
+			}
+		} else if r.hasRef(branch) {
+			t.Errorf("%s: expected no local release branch", n)
+		}
+		if j, err := mgr.loadJournal(); err != nil || j != nil {
+			t.Errorf("%s: expected no journal, got %v %v", n, j, err)
+		}
+		r.close()
+	}
+}
+
+func TestReleaseResume(t *testing.T) {
+	r := newTestRepo(t)
+	defer r.close()
+	mgr := r.manager(misc.BranchPerMinor)
+	r.refuse("refs/tags/")
+	if err := mgr.Release(
+		mgr.FindModule("kyaml"), semver.Minor, nil, true); err == nil {
+		t.Fatal("expected the release to fail")
+	}
+	// The branch was pushed, so the release is left for resume or abort.
+	j, err := mgr.loadJournal()
+	if err != nil || j == nil {
+		t.Fatalf("expected a journal, got %v %v", j, err)
+	}
+	if !r.hasRef("refs/tags/kyaml/v0.2.0") {
+		t.Fatal("expected a local tag")
+	}
+	if !r.remoteHasRef("refs/heads/release-kyaml-v0.2") {
+		t.Fatal("expected the release branch on the remote")
+	}
+
+	r.allow()
+	if err = mgr.Resume(true); err != nil {
+		t.Fatal(err)
+	}
+	if !r.remoteHasRef("refs/tags/kyaml/v0.2.0") {
+		t.Error("expected the tag on the remote")
+	}
+	if actual := r.git("rev-parse", "--abbrev-ref", "HEAD"); actual != "main" {
+		t.Errorf("expected main checked out, got %s", actual)
+	}
+	if j, err = mgr.loadJournal(); err != nil || j != nil {
+		t.Errorf("expected no journal, got %v %v", j, err)
+	}
+	if err = mgr.Resume(true); err == nil {
+		t.Error("expected nothing to resume")
+	}
+}
+
+func TestReleaseAbort(t *testing.T) {
+	r := newTestRepo(t)
+	defer r.close()
+	head := r.git("rev-parse", "HEAD")
+	mgr := r.manager(misc.BranchPerMinor)
+	r.refuse("refs/tags/")
+	if err := mgr.Release(
+		mgr.FindModule("kyaml"), semver.Minor, nil, true); err == nil {
+		t.Fatal("expected the release to fail")
+	}
+	if err := mgr.Abort(true); err != nil {
+		t.Fatal(err)
+	}
+	if r.hasRef("refs/tags/kyaml/v0.2.0") {
+		t.Error("expected no local tag")
+	}
+	if actual := r.git("rev-parse", "--abbrev-ref", "HEAD"); actual != "main" {
+		t.Errorf("expected main checked out, got %s", actual)
+	}
+	if actual := r.git("rev-parse", "HEAD"); actual != head {
+		t.Errorf("expected HEAD %s, got %s", head, actual)
+	}
+	// The pushed release branch stays.
+	if !r.hasRef("refs/heads/release-kyaml-v0.2") {
+		t.Error("expected the pushed release branch to remain")
+	}
+	if j, err := mgr.loadJournal(); err != nil || j != nil {
+		t.Errorf("expected no journal, got %v %v", j, err)
+	}
+}
+
+func TestReleaseChangelog(t *testing.T) {
+	r := newTestRepo(t)
+	defer r.close()
+	mgr := r.manager(misc.BranchPerMinor)
+	mgr.cfg.Changelog = true
+	if err := mgr.Release(
+		mgr.FindModule("kyaml"), semver.Minor, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	r.write("kyaml/walk.go", "package kyaml\n\n// Walk walks.\nfunc Walk() {}\n")
+	r.commit("fix: document Walk")
+	r.git("push", "-q", "origin", "main")
+	// The next release on the same line.
+	mgr = r.manager(misc.BranchPerMinor)
+	mgr.cfg.Changelog = true
+	if err := mgr.Release(
+		mgr.FindModule("kyaml"), semver.Patch, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{
+		"refs/tags/kyaml/v0.2.0", "refs/tags/kyaml/v0.2.1"} {
+		if !r.remoteHasRef(ref) {
+			t.Errorf("expected remote to have %s", ref)
+		}
+	}
+	for _, rev := range []string{"main", "kyaml/v0.2.1"} {
+		changelog := r.remoteGit("show", rev+":kyaml/CHANGELOG.md")
+		for _, v := range []string{"v0.2.0", "v0.2.1"} {
+			if !strings.Contains(changelog, v) {
+				t.Errorf("expected the changelog at %s to have %s, got\n%s",
+					rev, v, changelog)
+			}
+		}
+	}
+	if actual := r.git("rev-parse", "--abbrev-ref", "HEAD"); actual != "main" {
+		t.Errorf("expected main checked out, got %s", actual)
+	}
+}
+
+func TestReleaseChangelogRollback(t *testing.T) {
+	r := newTestRepo(t)
+	defer r.close()
+	main := r.git("rev-parse", "main")
+	r.git("checkout", "-q", "-b", "feature")
+	mgr := r.manager(misc.BranchPerMinor)
+	mgr.cfg.Changelog = true
+	r.refuse("refs/heads/main")
+	if err := mgr.Release(
+		mgr.FindModule("kyaml"), semver.Minor, nil, true); err == nil {
+		t.Fatal("expected the release to fail")
+	}
+	if actual := r.git("rev-parse", "--abbrev-ref", "HEAD"); actual != "feature" {
+		t.Errorf("expected feature checked out, got %s", actual)
+	}
+	if actual := r.git("rev-parse", "main"); actual != main {
+		t.Errorf("expected main restored to %s, got %s", main, actual)
+	}
+	if r.hasRef("refs/heads/release-kyaml-v0.2") {
+		t.Error("expected no local release branch")
+	}
+	if j, err := mgr.loadJournal(); err != nil || j != nil {
+		t.Errorf("expected no journal, got %v %v", j, err)
+	}
+}
@@ -2,12 +2,24 @@ package repo
 
 import (
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/monopole/gorepomod/internal/apidiff"
+	"github.com/monopole/gorepomod/internal/config"
 	"github.com/monopole/gorepomod/internal/edit"
 	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/graph"
 	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/mod"
+	"github.com/monopole/gorepomod/internal/naming"
 	"github.com/monopole/gorepomod/internal/semver"
+	"github.com/monopole/gorepomod/internal/utils"
+	"github.com/monopole/gorepomod/internal/work"
+	"github.com/monopole/gorepomod/listing"
 )
 
 // Manager manages a git repo.
@@ -20,8 +32,24 @@ type Manager struct {
 	// and pushing release branches.
 	remoteName misc.TrackedRepo
 
+	// The branch from which releases are made, e.g. "main".
+	mainBranch string
+
+	// Names release branches and tags.
+	names *naming.Scheme
+
+	// Determines the git steps taken to release.
+	strategy misc.ReleaseStrategy
+
+	// All the tagged versions of each module, newest first.
+	versionsLocal  misc.VersionMap
+	versionsRemote misc.VersionMap
+
 	// The list of known Go modules in the repo.
 	modules misc.LesModules
+
+	// Settings from the repository's config file.
+	cfg *config.Config
 }
 
 func (mgr *Manager) AbsPath() string {
@@ -32,11 +60,42 @@ func (mgr *Manager) RepoPath() string {
 	return mgr.dg.RepoPath()
 }
 
+// DefaultBump returns the configured bump for the module,
+// used when no bump is specified.
+func (mgr *Manager) DefaultBump(m misc.LaModule) semver.SvBump {
+	return mgr.cfg.Bump(m.ShortName())
+}
+
 func (mgr *Manager) FindModule(
 	target misc.ModuleShortName) misc.LaModule {
 	return mgr.modules.Find(target)
 }
 
+// FindModuleFrom finds a module given either its short name,
+// or the path to its directory relative to dir, an absolute
+// path in the repository (typically the working directory).
+// Paths must start with "." or "..", e.g. ".", "../kyaml".
+// Short names are also tried relative to dir.
+func (mgr *Manager) FindModuleFrom(
+	dir string, name misc.ModuleShortName) misc.LaModule {
+	n := string(name)
+	isPath := n == "." || n == ".." ||
+		strings.HasPrefix(n, "./") || strings.HasPrefix(n, "../")
+	if !isPath {
+		if m := mgr.modules.Find(name); m != nil {
+			return m
+		}
+	}
+	rel, err := filepath.Rel(mgr.AbsPath(), filepath.Join(dir, n))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil
+	}
+	if rel == "." {
+		return mgr.modules.Find(misc.ModuleAtTop)
+	}
+	return mgr.modules.Find(misc.ModuleShortName(filepath.ToSlash(rel)))
+}
+
 func (mgr *Manager) Tidy(doIt bool) error {
 	return mgr.modules.Apply(func(m misc.LaModule) error {
 		return edit.New(m, doIt).Tidy()
@@ -46,8 +105,8 @@ func (mgr *Manager) Tidy(doIt bool) error {
 func (mgr *Manager) Pin(
 	doIt bool, target misc.LaModule, newV semver.SemVer) error {
 	return mgr.modules.Apply(func(m misc.LaModule) error {
-		if yes, oldVersion := m.DependsOn(target); yes {
-			return edit.New(m, doIt).Pin(target, oldVersion, newV)
+		if yes, _ := m.DependsOn(target); yes {
+			return edit.New(m, doIt).Pin(target, newV)
 		}
 		return nil
 	})
@@ -62,6 +121,94 @@ func (mgr *Manager) UnPin(doIt bool, target misc.LaModule) error {
 	})
 }
 
+// UnPinWorkspace is an alternative to UnPin, making
+// modules that depend on the target use the target's local
+// code by adding them, and the target, to the go.work file
+// in the repository root.  No go.mod file is changed.
+func (mgr *Manager) UnPinWorkspace(
+	doIt bool, target misc.LaModule) error {
+	w, err := work.LoadOrNew(mgr.AbsPath())
+	if err != nil {
+		return err
+	}
+	dirs := []string{moduleDir(target)}
+	for _, dep := range mgr.modules.GetAllThatDependOn(target) {
+		dirs = append(dirs, moduleDir(dep.M))
+	}
+	added := w.Use(dirs...)
+	if len(added) == 0 {
+		fmt.Printf("%s already uses %v\n", work.FileName, dirs)
+		return nil
+	}
+	fmt.Printf("adding %v to %s\n", added, w.Path())
+	return w.Write(doIt)
+}
+
+// WorkInit creates a go.work file using every module in the repo.
+func (mgr *Manager) WorkInit(doIt bool) error {
+	if work.Exists(mgr.AbsPath()) {
+		return fmt.Errorf(
+			"%s already exists in %s", work.FileName, mgr.AbsPath())
+	}
+	return mgr.WorkSync(doIt)
+}
+
+// WorkSync creates or updates the go.work file so that
+// it uses every module in the repo, and nothing else.
+func (mgr *Manager) WorkSync(doIt bool) error {
+	w, err := work.LoadOrNew(mgr.AbsPath())
+	if err != nil {
+		return err
+	}
+	added, dropped := w.Sync(mgr.moduleDirs())
+	for _, d := range added {
+		fmt.Printf("adding %s\n", d)
+	}
+	for _, d := range dropped {
+		fmt.Printf("dropping %s\n", d)
+	}
+	return w.Write(doIt)
+}
+
+// WorkCheck validates the go.work file against the modules in the repo.
+func (mgr *Manager) WorkCheck() error {
+	if !work.Exists(mgr.AbsPath()) {
+		return fmt.Errorf("no %s in %s", work.FileName, mgr.AbsPath())
+	}
+	w, err := work.Load(mgr.AbsPath())
+	if err != nil {
+		return err
+	}
+	problems := w.Check(mgr.moduleDirs())
+	if len(problems) > 0 {
+		return fmt.Errorf(
+			"%s is out of date:\n  %s",
+			w.Path(), strings.Join(problems, "\n  "))
+	}
+	fmt.Printf("%s uses all %d modules\n", w.Path(), len(mgr.modules))
+	return nil
+}
+
+func (mgr *Manager) moduleDirs() (result []string) {
+	for _, m := range mgr.modules {
+		result = append(result, moduleDir(m))
+	}
+	return
+}
+
+// workspaceModules returns the short names of the modules
+// used by the repository's go.work file, if there is one.
+func (mgr *Manager) workspaceModules() (result []string) {
+	for _, u := range mgr.dg.WorkspaceUses() {
+		for _, m := range mgr.modules {
+			if work.DirOf(moduleDir(m)) == u {
+				result = append(result, string(m.ShortName()))
+			}
+		}
+	}
+	return
+}
+
 func hasUnPinnedDeps(m misc.LaModule) string {
 	if len(m.GetReplacements()) > 0 {
 		return "yes"
@@ -69,35 +216,136 @@ func hasUnPinnedDeps(m misc.LaModule) string {
 	return ""
 }
 
-func (mgr *Manager) List() error {
-	fmt.Printf("   src path: %s\n", mgr.dg.SrcPath())
+func (mgr *Manager) List(output listing.Format) error {
+	if output != listing.Table {
+		r, err := mgr.Listing()
+		if err != nil {
+			return err
+		}
+		return r.Write(os.Stdout, output)
+	}
 	fmt.Printf("  repo path: %s\n", mgr.RepoPath())
+	fmt.Printf("   abs path: %s\n", mgr.AbsPath())
 	fmt.Printf("     remote: %s\n", mgr.remoteName)
+	fmt.Printf("main branch: %s\n", mgr.mainBranch)
+	if mgr.dg.WorkspaceUses() != nil {
+		fmt.Printf(
+			"  workspace: %s uses %s\n",
+			work.FileName, strings.Join(mgr.workspaceModules(), ", "))
+	}
 	format := "%-" +
 		strconv.Itoa(mgr.modules.LenLongestName()+2) +
-		"s%-11s%-11s%17s  %s\n"
+		"s%-11s%-11s%-12s%17s  %s\n"
 	fmt.Printf(
-		format, "NAME", "LOCAL", "REMOTE",
+		format, "NAME", "LOCAL", "REMOTE", "UNRELEASED",
 		"HAS-UNPINNED-DEPS", "INTRA-REPO-DEPENDENCIES")
-	return mgr.modules.Apply(func(m misc.LaModule) error {
+	gr := git.NewQuiet(mgr.AbsPath(), true)
+	var warnings []string
+	err := mgr.modules.Apply(func(m misc.LaModule) error {
+		c, err := mgr.changesSinceLatestTag(gr, m)
+		if err != nil {
+			return err
+		}
 		fmt.Printf(
 			format, m.ShortName(),
 			m.VersionLocal().Pretty(),
 			m.VersionRemote().Pretty(),
+			c,
 			hasUnPinnedDeps(m),
 			mgr.modules.InternalDeps(m))
+		for _, w := range append(moduleWarnings(m), mgr.pseudoWarnings(gr, m)...) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", m.ShortName(), w))
+		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+	return nil
 }
 
-func determineBranchAndTag(
-	m misc.LaModule, v semver.SemVer) (string, string) {
-	if m.ShortName() == misc.ModuleAtTop {
-		return fmt.Sprintf("release-%s", v.BranchLabel()), v.String()
+// moduleWarnings describes problems with the module that
+// don't stop gorepomod, but should be fixed.
+func moduleWarnings(m misc.LaModule) (result []string) {
+	// The remote tag is checked only if it differs,
+	// to avoid repeating a warning.
+	for _, v := range []semver.SemVer{m.VersionLocal(), m.VersionRemote()} {
+		if v.IsZero() {
+			continue
+		}
+		if err := mod.CheckPathMajor(m.ImportPath(), v); err != nil {
+			result = append(result, "tag "+v.String()+": "+err.Error())
+		}
+		if m.VersionRemote().Equals(m.VersionLocal()) {
+			break
+		}
+	}
+	return
+}
+
+// Listing returns a description of the repo and its modules.
+func (mgr *Manager) Listing() (*listing.Repo, error) {
+	r := &listing.Repo{
+		RepoPath:   mgr.RepoPath(),
+		AbsPath:    mgr.AbsPath(),
+		Remote:     string(mgr.remoteName),
+		MainBranch: mgr.mainBranch,
+		Workspace:  mgr.workspaceModules(),
+	}
+	gr := git.NewQuiet(mgr.AbsPath(), true)
+	err := mgr.modules.Apply(func(m misc.LaModule) error {
+		c, err := mgr.changesSinceLatestTag(gr, m)
+		if err != nil {
+			return err
+		}
+		lm := listing.Module{
+			ShortName:         string(m.ShortName()),
+			ImportPath:        m.ImportPath(),
+			VersionLocal:      m.VersionLocal().Pretty(),
+			VersionRemote:     m.VersionRemote().Pretty(),
+			NeedsRelease:      c.needsRelease,
+			UnreleasedCommits: c.commits,
+			Replacements:      m.GetReplacements(),
+			Warnings: append(
+				moduleWarnings(m), mgr.pseudoWarnings(gr, m)...),
+		}
+		for _, dep := range mgr.modules.InternalDeps(m) {
+			lm.Dependencies = append(lm.Dependencies, listing.Dependency{
+				ShortName:  string(dep.M.ShortName()),
+				ImportPath: dep.M.ImportPath(),
+				Version:    dep.V.Pretty(),
+				Commit:     dep.V.Revision(),
+			})
+		}
+		r.Modules = append(r.Modules, lm)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Graph writes the intra-repo module dependency graph.
+func (mgr *Manager) Graph(f graph.Format) error {
+	return graph.Write(os.Stdout, mgr.modules, f)
+}
+
+func (mgr *Manager) determineBranchAndTag(
+	m misc.LaModule, v semver.SemVer) (branch, tag string, err error) {
+	branch, err = mgr.names.Branch(m.ShortName(), m.ImportPath(), v)
+	if err != nil {
+		return
 	}
-	return fmt.Sprintf(
-			"release-%s-%s", m.ShortName(), v.BranchLabel()),
-		string(m.ShortName()) + "/" + v.String()
+	tag, err = mgr.tag(m, v)
+	return
+}
+
+func (mgr *Manager) tag(m misc.LaModule, v semver.SemVer) (string, error) {
+	return mgr.names.Tag(m.ShortName(), m.ImportPath(), v)
 }
 
 func (mgr *Manager) Debug(_ misc.LaModule, doIt bool) error {
@@ -107,11 +355,23 @@ func (mgr *Manager) Debug(_ misc.LaModule, doIt bool) error {
 
 // Release supports a gitlab flow style release process.
 //
-// * All development happens in the branch named "master".
+// * All development happens in the main branch (e.g. "main").
 // * Each minor release gets its own branch.
-// *
+//
+// Other release strategies give each major release its own
+// branch, or use no release branches, tagging the main branch.
+//
+// The changes to the module's exported API since its latest
+// tag, if already known (e.g. from SuggestBump), are used to
+// refuse an incompatible patch release; if nil, they're found.
 func (mgr *Manager) Release(
-	target misc.LaModule, bump semver.SvBump, doIt bool) error {
+	target misc.LaModule, bump semver.SvBump,
+	api *apidiff.Report, doIt bool) error {
+
+	if mgr.cfg.NeverRelease(target.ShortName()) {
+		return fmt.Errorf(
+			"module %q is configured to never be released", target.ShortName())
+	}
 
 	if reps := target.GetReplacements(); len(reps) > 0 {
 		return fmt.Errorf(
@@ -119,7 +379,34 @@ func (mgr *Manager) Release(
 			target.ShortName(), reps)
 	}
 
+	if bump == semver.Final && !target.VersionLocal().IsPreRelease() {
+		return fmt.Errorf(
+			"cannot finalize %q, as its latest version %s isn't a pre-release",
+			target.ShortName(), target.VersionLocal())
+	}
+
 	newVersion := target.VersionLocal().Bump(bump)
+	if err := mod.CheckPathMajor(target.ImportPath(), newVersion); err != nil {
+		return fmt.Errorf("refusing to release %q: %v", target.ShortName(), err)
+	}
+
+	if bump == semver.Patch && !target.VersionLocal().IsZero() {
+		if api == nil {
+			var err error
+			api, err = mgr.apiChanges(target)
+			if err != nil {
+				// The check is a safeguard, not a requirement.
+				fmt.Printf(
+					"warning: cannot check %q for incompatible API changes: %v\n",
+					target.ShortName(), err)
+			}
+		}
+		if api != nil && len(api.Incompatible) > 0 {
+			return fmt.Errorf(
+				"refusing a patch release of %q, which has %s",
+				target.ShortName(), api)
+		}
+	}
 
 	if newVersion.Equals(target.VersionRemote()) {
 		return fmt.Errorf(
@@ -131,56 +418,470 @@ func (mgr *Manager) Release(
 			newVersion, target.VersionRemote())
 	}
 
-	gr := git.NewLoud(mgr.AbsPath(), doIt)
-
-	relBranch, relTag := determineBranchAndTag(target, newVersion)
+	var err error
+	j := &journal{
+		Module:   string(target.ShortName()),
+		Version:  newVersion.String(),
+		Strategy: mgr.strategy,
+		Signing:  mgr.signing(),
+	}
+	if mgr.strategy == misc.Trunk {
+		j.Tag, err = mgr.tag(target, newVersion)
+		if err != nil {
+			return err
+		}
+		fmt.Printf(
+			"Releasing %s from %s, stepping from %s to %s\n",
+			target.ShortName(), mgr.mainBranch,
+			target.VersionLocal(), newVersion)
+	} else {
+		j.Branch, j.Tag, err = mgr.determineBranchAndTag(target, newVersion)
+		if err != nil {
+			return err
+		}
+		fmt.Printf(
+			"Releasing %s, stepping from %s to %s\n",
+			target.ShortName(), target.VersionLocal(), newVersion)
+	}
 
-	fmt.Printf(
-		"Releasing %s, stepping from %s to %s\n",
-		target.ShortName(), target.VersionLocal(), newVersion)
+	if !target.VersionLocal().IsZero() {
+		j.PreviousTag, err = mgr.tag(target, target.VersionLocal())
+		if err != nil {
+			return err
+		}
+	}
+	if mgr.cfg.Changelog {
+		if mgr.strategy == misc.Trunk {
+			return fmt.Errorf(
+				"cannot update a changelog with the %q release strategy, "+
+					"which never commits", misc.Trunk)
+		}
+		j.Changelog = path.Join(moduleDir(target), changelogFileName)
+	}
 
+	gr := git.NewLoud(mgr.AbsPath(), doIt)
 	if err := gr.AssureCleanWorkspace(); err != nil {
 		return err
 	}
-	if err := gr.FetchRemote(mgr.remoteName); err != nil {
+	if err := mgr.startJournal(gr, j, doIt); err != nil {
 		return err
 	}
-	if err := gr.CheckoutMainBranch(); err != nil {
-		return err
+	return mgr.runSteps(gr, j, mgr.releaseSteps(gr, j))
+}
+
+// releaseSteps returns the steps of the release in the journal.
+//
+// Steps taken before a failure aren't taken again when
+// resuming, except for those that merely check things
+// out, which must be redone to pick up where things left off.
+func (mgr *Manager) releaseSteps(gr *git.Runner, j *journal) []step {
+	fetch := step{
+		name: "fetch remote",
+		do: func() error {
+			return gr.FetchRemote(mgr.remoteName)
+		},
 	}
-	if err := gr.MergeFromRemoteMain(mgr.remoteName); err != nil {
-		return err
+	pushTag := step{
+		name:   stepPushTag,
+		remote: true,
+		do: func() error {
+			return gr.PushTagToRemote(mgr.remoteName, j.Tag)
+		},
 	}
-	if err := gr.AssureCleanWorkspace(); err != nil {
-		return err
+	if j.Strategy == misc.Trunk {
+		// Tag the remote's main branch, as just fetched,
+		// leaving the local branches and working tree alone.
+		remoteMain := git.RemoteBranch(mgr.remoteName, mgr.mainBranch)
+		return []step{
+			fetch,
+			{
+				name: stepPreflight,
+				do: func() error {
+					return mgr.preflightStep(gr, j, remoteMain)
+				},
+			},
+			{
+				name: stepNotes,
+				do: func() error {
+					return mgr.collectChanges(gr, j, remoteMain)
+				},
+			},
+			{
+				name: stepTag,
+				do: func() error {
+					return gr.CreateLocalReleaseTag(
+						j.Tag, releaseMessage(j.Tag, mgr.mainBranch, j.Changes),
+						remoteMain, j.Signing)
+				},
+			},
+			pushTag,
+		}
 	}
-	if err := gr.CheckoutReleaseBranch(mgr.remoteName, relBranch); err != nil {
-		return err
+	checkoutMain := step{
+		name:   "checkout main branch",
+		repeat: true,
+		do: func() error {
+			return gr.CheckoutMainBranch(mgr.mainBranch)
+		},
 	}
-	if err := gr.MergeFromRemoteMain(mgr.remoteName); err != nil {
-		return err
+	steps := []step{
+		fetch,
+		checkoutMain,
+		{
+			name: "merge remote main branch",
+			do: func() error {
+				return gr.MergeFromRemoteMain(mgr.remoteName, mgr.mainBranch)
+			},
+		},
+		{
+			name:   "assure clean workspace",
+			repeat: true,
+			do:     gr.AssureCleanWorkspace,
+		},
+		{
+			name: stepNotes,
+			do: func() error {
+				return mgr.collectChanges(gr, j, "HEAD")
+			},
+		},
 	}
-	if err := gr.PushBranchToRemote(mgr.remoteName, relBranch); err != nil {
-		return err
+	if j.Changelog != "" {
+		// The changelog is committed to the main branch, which
+		// the release branch then takes in, so that later
+		// releases can still fast forward the release branch.
+		steps = append(steps, step{
+			name: stepChangelog,
+			do: func() error {
+				return mgr.updateChangelog(gr, j)
+			},
+		})
 	}
-	if err := gr.CreateLocalReleaseTag(relTag, relBranch); err != nil {
-		return err
+	steps = append(steps, []step{
+		{
+			name:   "checkout release branch",
+			repeat: true,
+			do: func() error {
+				return gr.CheckoutReleaseBranch(mgr.remoteName, j.Branch)
+			},
+		},
+		{
+			name: "merge main branch into release branch",
+			do: func() error {
+				return gr.MergeFromMain(mgr.mainBranch)
+			},
+		},
+		{
+			// The working tree is what's tagged.
+			name: stepPreflight,
+			do: func() error {
+				return mgr.preflightStep(gr, j, "")
+			},
+		},
+	}...)
+	if j.Changelog != "" {
+		steps = append(steps, step{
+			name:   stepPushMain,
+			remote: true,
+			do: func() error {
+				return gr.PushMainBranchToRemote(mgr.remoteName, mgr.mainBranch)
+			},
+		})
 	}
-	if err := gr.PushTagToRemote(mgr.remoteName, relTag); err != nil {
-		return err
+	return append(steps, []step{
+		{
+			name:   stepPushBranch,
+			remote: true,
+			do: func() error {
+				return gr.PushBranchToRemote(mgr.remoteName, j.Branch)
+			},
+		},
+		{
+			name: stepTag,
+			do: func() error {
+				return gr.CreateLocalReleaseTag(
+					j.Tag, releaseMessage(j.Tag, j.Branch, j.Changes),
+					"", j.Signing)
+			},
+		},
+		pushTag,
+		checkoutMain,
+	}...)
+}
+
+// ReleaseAll releases the given modules in dependency order,
+// each bumped per bumpFor.
+// If no modules are given, it releases every module with changes
+// since its most recent local tag, save those configured to
+// never be released.
+//
+// After each release, every in-repo module depending on the
+// freshly released module is pinned to the new version, and the
+// change is committed and pushed to the remote main branch, so that
+// releases later in the plan pick it up.
+func (mgr *Manager) ReleaseAll(
+	targets misc.LesModules,
+	bumpFor func(misc.LaModule) semver.SvBump, doIt bool) error {
+	if len(targets) == 0 {
+		changed, err := mgr.modulesWithUnreleasedChanges()
+		if err != nil {
+			return err
+		}
+		for _, m := range changed {
+			if !mgr.cfg.NeverRelease(m.ShortName()) {
+				targets = append(targets, m)
+			}
+		}
+		if len(targets) == 0 {
+			fmt.Println("Nothing to release.")
+			return nil
+		}
 	}
-	if err := gr.CheckoutMainBranch(); err != nil {
+	plan, err := mgr.modules.ReleaseOrder(targets)
+	if err != nil {
 		return err
 	}
+	for _, m := range plan {
+		if mgr.cfg.NeverRelease(m.ShortName()) {
+			return fmt.Errorf(
+				"module %q is configured to never be released", m.ShortName())
+		}
+		if reps := m.GetReplacements(); len(reps) > 0 {
+			return fmt.Errorf(
+				"to release %q, first pin these replacements: %v",
+				m.ShortName(), reps)
+		}
+		v := m.VersionLocal().Bump(bumpFor(m))
+		if err := mod.CheckPathMajor(m.ImportPath(), v); err != nil {
+			return fmt.Errorf("refusing to release %q: %v", m.ShortName(), err)
+		}
+	}
+
+	fmt.Println("Release plan:")
+	for i, m := range plan {
+		fmt.Printf(
+			"  %2d. %s  %s -> %s\n", i+1, m.ShortName(),
+			m.VersionLocal(), m.VersionLocal().Bump(bumpFor(m)))
+	}
+
+	gr := git.NewLoud(mgr.AbsPath(), doIt)
+	for _, m := range plan {
+		bump := bumpFor(m)
+		newVersion := m.VersionLocal().Bump(bump)
+		if err := mgr.Release(m, bump, nil, doIt); err != nil {
+			return err
+		}
+		dependents := mgr.modules.GetAllThatDependOn(m)
+		if len(dependents) == 0 {
+			continue
+		}
+		if mgr.strategy == misc.Trunk {
+			// Release left the local branches alone; pin on an
+			// up to date main branch.
+			if err := gr.CheckoutMainBranch(mgr.mainBranch); err != nil {
+				return err
+			}
+			err := gr.MergeFromRemoteMain(mgr.remoteName, mgr.mainBranch)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Printf(
+			"Pinning %s to %s in %s\n", m.ShortName(), newVersion, dependents)
+		if err := mgr.Pin(doIt, m, newVersion); err != nil {
+			return err
+		}
+		tag, err := mgr.tag(m, newVersion)
+		if err != nil {
+			return err
+		}
+		// Tidying may have made a go.sum file, which must be
+		// committed too, else the next release finds the
+		// workspace unclean.
+		var files []string
+		for _, dep := range dependents {
+			for _, f := range []string{goModFile, goSumFile} {
+				p := path.Join(moduleDir(dep.M), f)
+				if utils.PathExists(filepath.Join(mgr.AbsPath(), p)) {
+					files = append(files, p)
+				}
+			}
+		}
+		if err := gr.CommitFiles("Pin to "+tag, files...); err != nil {
+			return err
+		}
+		if err := gr.PushMainBranchToRemote(mgr.remoteName, mgr.mainBranch); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// modulesWithUnreleasedChanges returns the modules having file
+// changes since their most recent local tag.
+func (mgr *Manager) modulesWithUnreleasedChanges() (
+	result misc.LesModules, err error) {
+	gr := git.NewQuiet(mgr.AbsPath(), true)
+	err = mgr.modules.Apply(func(m misc.LaModule) error {
+		c, err := mgr.changesSinceLatestTag(gr, m)
+		if err != nil {
+			return err
+		}
+		if c.needsRelease {
+			result = append(result, m)
+		}
+		return nil
+	})
+	return
+}
+
+// moduleChanges summarizes changes made to a module
+// since its most recent local tag.
+type moduleChanges struct {
+	// The module's files differ from those at the tag.
+	needsRelease bool
+	// The number of commits touching the module's files.
+	commits int
+}
+
+func (c moduleChanges) String() string {
+	if !c.needsRelease {
+		return ""
+	}
+	return strconv.Itoa(c.commits)
+}
+
+func (mgr *Manager) changesSinceLatestTag(
+	gr *git.Runner, m misc.LaModule) (c moduleChanges, err error) {
+	tag := ""
+	if !m.VersionLocal().IsZero() {
+		tag, err = mgr.tag(m, m.VersionLocal())
+		if err != nil {
+			return
+		}
+	}
+	specs := mgr.pathSpecs(m)
+	c.needsRelease, err = gr.HasChangesSince(tag, specs)
+	if err != nil {
+		return
+	}
+	c.commits, err = gr.CountCommitsSince(tag, specs)
+	return
+}
+
+// Changed reports, for each module, whether it has changes
+// since its most recent local tag, and how many commits made them.
+func (mgr *Manager) Changed() error {
+	gr := git.NewQuiet(mgr.AbsPath(), true)
+	format := "%-" +
+		strconv.Itoa(mgr.modules.LenLongestName()+2) +
+		"s%-11s%-15s%s\n"
+	fmt.Printf(format, "NAME", "LOCAL", "NEEDS-RELEASE", "COMMITS")
+	return mgr.modules.Apply(func(m misc.LaModule) error {
+		c, err := mgr.changesSinceLatestTag(gr, m)
+		if err != nil {
+			return err
+		}
+		needs := ""
+		if c.needsRelease {
+			needs = "yes"
+		}
+		fmt.Printf(
+			format, m.ShortName(), m.VersionLocal().Pretty(),
+			needs, strconv.Itoa(c.commits))
+		return nil
+	})
+}
+
+// SuggestBump reports changes to the module's exported API
+// since its most recent local tag, and returns the bump
+// appropriate to releasing those changes, and the changes.
+func (mgr *Manager) SuggestBump(
+	target misc.LaModule) (semver.SvBump, *apidiff.Report, error) {
+	r, err := mgr.apiChanges(target)
+	if err != nil {
+		return semver.Patch, nil, err
+	}
+	v := target.VersionLocal()
+	bump := r.Bump(v)
+	fmt.Printf("Changes to %s since %s:\n", target.ShortName(), v)
+	fmt.Print(r)
+	fmt.Printf(
+		"suggested bump: %s (%s -> %s)\n",
+		strings.ToLower(bump.String()), v, v.Bump(bump))
+	return bump, r, nil
+}
+
+// apiChanges compares the module's exported API at its
+// most recent local tag with the API in the working tree.
+func (mgr *Manager) apiChanges(
+	target misc.LaModule) (*apidiff.Report, error) {
+	if target.VersionLocal().IsZero() {
+		return nil, fmt.Errorf(
+			"module %q has no local tag to compare against", target.ShortName())
+	}
+	tag, err := mgr.tag(target, target.VersionLocal())
+	if err != nil {
+		return nil, err
+	}
+	gr := git.NewQuiet(mgr.AbsPath(), true)
+	files, err := gr.FilesAt(tag, mgr.pathSpecs(target))
+	if err != nil {
+		return nil, err
+	}
+	dir := moduleDir(target)
+	if dir != "." {
+		moved := make(map[string][]byte)
+		for n, content := range files {
+			moved[strings.TrimPrefix(n, dir+"/")] = content
+		}
+		files = moved
+	}
+	oldApi, err := apidiff.FromFiles(files)
+	if err != nil {
+		return nil, fmt.Errorf("at %s: %v", tag, err)
+	}
+	newApi, err := apidiff.FromDir(filepath.Join(mgr.AbsPath(), dir))
+	if err != nil {
+		return nil, err
+	}
+	return apidiff.Compare(oldApi, newApi), nil
+}
+
+// moduleDir is the module's directory relative to the repository root.
+func moduleDir(m misc.LaModule) string {
+	if m.ShortName() == misc.ModuleAtTop {
+		return "."
+	}
+	return string(m.ShortName())
+}
+
+// pathSpecs returns git pathspecs selecting the files of the
+// given module, excluding the directories of nested modules.
+func (mgr *Manager) pathSpecs(target misc.LaModule) []string {
+	dir := moduleDir(target)
+	result := []string{dir}
+	for _, m := range mgr.modules {
+		if m.ShortName() == target.ShortName() ||
+			m.ShortName() == misc.ModuleAtTop {
+			continue
+		}
+		if target.ShortName() == misc.ModuleAtTop ||
+			strings.HasPrefix(string(m.ShortName()), dir+"/") {
+			result = append(result, ":(exclude)"+string(m.ShortName()))
+		}
+	}
+	return result
+}
+
 func (mgr *Manager) UnRelease(target misc.LaModule, doIt bool) error {
 	fmt.Printf(
 		"Unreleasing %s/%s\n",
 		target.ShortName(), target.VersionRemote())
 
-	_, tag := determineBranchAndTag(target, target.VersionRemote())
+	tag, err := mgr.tag(target, target.VersionRemote())
+	if err != nil {
+		return err
+	}
 
 	gr := git.NewLoud(mgr.AbsPath(), doIt)
 
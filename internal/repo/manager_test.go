@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+func TestReleaseFinal(t *testing.T) {
+	var testCases = map[string]struct {
+		// A tag of kyaml made at HEAD before releasing.
+		tag      string
+		expected string
+		err      string
+	}{
+		"preRelease": {
+			tag:      "kyaml/v0.2.0-rc.1",
+			expected: "refs/tags/kyaml/v0.2.0",
+		},
+		"notPreRelease": {
+			err: `cannot finalize "kyaml", as its latest version v0.1.0 isn't a pre-release`,
+		},
+		"localOnlyRelease": {
+			tag: "kyaml/v0.2.0",
+			err: `cannot finalize "kyaml", as its latest version v0.2.0 isn't a pre-release`,
+		},
+	}
+	for n, tc := range testCases {
+		r := newTestRepo(t)
+		if tc.tag != "" {
+			r.git("tag", "-a", "-m", "Release "+tc.tag, tc.tag)
+		}
+		mgr := r.manager(misc.Trunk)
+		err := mgr.Release(mgr.FindModule("kyaml"), semver.Final, nil, true)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: expected error %q, got %v", n, tc.err, err)
+			}
+			if j, _ := mgr.loadJournal(); j != nil {
+				t.Errorf("%s: expected no journal", n)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", n, err)
+		} else if !r.remoteHasRef(tc.expected) {
+			t.Errorf("%s: expected remote to have %s", n, tc.expected)
+		}
+		r.close()
+	}
+}
+
+func TestReleaseAll(t *testing.T) {
+	var testCases = map[string]struct {
+		strategy misc.ReleaseStrategy
+	}{
+		"trunk": {
+			strategy: misc.Trunk,
+		},
+		"branchPerMinor": {
+			strategy: misc.BranchPerMinor,
+		},
+	}
+	for n, tc := range testCases {
+		r := newTestRepo(t)
+		r.write("api/go.mod", "module "+testRepoPath+"/api\n\ngo 1.15\n\n"+
+			"require "+testRepoPath+"/kyaml v0.1.0\n")
+		r.write("api/api.go", "package api\n\nimport \""+testRepoPath+
+			"/kyaml\"\n\nfunc Walk() { kyaml.Walk() }\n")
+		r.commit("Add api")
+		r.git("tag", "-a", "-m", "Release api/v0.1.0", "api/v0.1.0")
+		r.git("push", "-q", "origin", "main", "api/v0.1.0")
+		r.serve("kyaml", "v0.2.0")
+		mgr := r.manager(tc.strategy)
+		err := mgr.ReleaseAll(
+			misc.LesModules{mgr.FindModule("api"), mgr.FindModule("kyaml")},
+			func(misc.LaModule) semver.SvBump { return semver.Minor }, true)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", n, err)
+			r.close()
+			continue
+		}
+		for _, tag := range []string{"kyaml/v0.2.0", "api/v0.2.0"} {
+			if !r.remoteHasRef("refs/tags/" + tag) {
+				t.Errorf("%s: expected remote to have tag %s", n, tag)
+			}
+		}
+		if actual := r.remoteGit(
+			"log", "-1", "--format=%s", "main"); actual != "Pin to kyaml/v0.2.0" {
+			t.Errorf("%s: expected the pin commit on main, got %q", n, actual)
+		}
+		goMod := r.remoteGit("show", "api/v0.2.0:api/go.mod")
+		if !strings.Contains(goMod, testRepoPath+"/kyaml v0.2.0") {
+			t.Errorf("%s: expected api/v0.2.0 to require kyaml v0.2.0, got\n%s",
+				n, goMod)
+		}
+		if r.remoteGit("show", "api/v0.2.0:api/go.sum") == "" {
+			t.Errorf("%s: expected api/v0.2.0 to have a go.sum", n)
+		}
+		if actual := r.git("status", "--porcelain"); actual != "" {
+			t.Errorf("%s: expected a clean workspace, got\n%s", n, actual)
+		}
+		r.close()
+	}
+}
+
+func TestChangesSinceLatestTag(t *testing.T) {
+	r := newTestRepo(t)
+	defer r.close()
+	// kyaml has one commit since kyaml/v0.1.0.
+	r.write("go.mod", "module "+testRepoPath+"\n\ngo 1.15\n")
+	r.write("multi.go", "package multi\n")
+	r.commit("Add top module")
+	r.git("tag", "-a", "-m", "Release v0.1.0", "v0.1.0")
+	r.write("kyaml/v2/go.mod", "module "+testRepoPath+"/kyaml/v2\n\ngo 1.15\n")
+	r.write("kyaml/v2/kyaml.go", "package kyaml\n")
+	r.commit("Add kyaml/v2")
+	r.git("tag", "-a", "-m", "Release kyaml/v2.0.0", "kyaml/v2.0.0")
+	r.write("api/go.mod", "module "+testRepoPath+"/api\n\ngo 1.15\n")
+	r.write("api/api.go", "package api\n")
+	r.commit("Add api")
+	// Changes to nested modules only.
+	r.write("kyaml/v2/walk.go", "package kyaml\n\nfunc Walk() {}\n")
+	r.commit("feat: add Walk to v2")
+	r.write("kyaml/v2/walk.go", "package kyaml\n\n// Walk walks.\nfunc Walk() {}\n")
+	r.commit("fix: document Walk in v2")
+
+	var testCases = map[string]struct {
+		module       misc.ModuleShortName
+		needsRelease bool
+		commits      int
+	}{
+		"top": {
+			module: misc.ModuleAtTop,
+		},
+		"parent": {
+			module:       "kyaml",
+			needsRelease: true,
+			commits:      1,
+		},
+		"nested": {
+			module:       "kyaml/v2",
+			needsRelease: true,
+			commits:      2,
+		},
+		"untagged": {
+			module:       "api",
+			needsRelease: true,
+			commits:      1,
+		},
+	}
+	mgr := r.manager(misc.Trunk)
+	gr := git.NewQuiet(r.dir, true)
+	for n, tc := range testCases {
+		m := mgr.FindModule(tc.module)
+		if m == nil {
+			t.Fatalf("%s: no module %q", n, tc.module)
+		}
+		c, err := mgr.changesSinceLatestTag(gr, m)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", n, err)
+			continue
+		}
+		if c.needsRelease != tc.needsRelease || c.commits != tc.commits {
+			t.Errorf("%s: expected %v and %d commits, got %v and %d commits",
+				n, tc.needsRelease, tc.commits, c.needsRelease, c.commits)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package repo
 
 import (
+	"github.com/monopole/gorepomod/internal/config"
 	"github.com/monopole/gorepomod/internal/misc"
 	"github.com/monopole/gorepomod/internal/mod"
+	"github.com/monopole/gorepomod/internal/naming"
 )
 
 // ManagerFactory is a collection of clean data needed to build
@@ -11,14 +13,24 @@ type ManagerFactory struct {
 	dg               *DotGitData
 	modules          []*protoModule
 	remoteName       misc.TrackedRepo
+	mainBranch       string
+	names            *naming.Scheme
 	versionMapLocal  misc.VersionMap
 	versionMapRemote misc.VersionMap
 }
 
-func (mf *ManagerFactory) NewRepoManager() *Manager {
+func (mf *ManagerFactory) NewRepoManager(
+	cfg *config.Config, strategy misc.ReleaseStrategy) *Manager {
 	result := &Manager{
+		cfg:        cfg,
+		strategy:   strategy,
 		dg:         mf.dg,
 		remoteName: mf.remoteName,
+		mainBranch: mf.mainBranch,
+		names:      mf.names,
+
+		versionsLocal:  mf.versionMapLocal,
+		versionsRemote: mf.versionMapRemote,
 	}
 	var modules misc.LesModules
 	for _, pm := range mf.modules {
@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/edit"
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+	"golang.org/x/mod/module"
+)
+
+// MigrateMajor prepares the module for its next major version,
+// e.g. v2 after v1, which needs a new module path, e.g.
+// sigs.k8s.io/kustomize/kyaml/v2.
+//
+// It changes the module directive, rewrites the imports of
+// the module's packages in every in-repo module, and has each
+// in-repo module depending on the module require the new path
+// at the new version instead.  As that version isn't released
+// yet, the requirement is replaced by the module's directory
+// (unless it already was).  Once the module is released, pin it.
+func (mgr *Manager) MigrateMajor(target misc.LaModule, doIt bool) error {
+	oldPath := target.ImportPath()
+	prefix, pathMajor, ok := module.SplitPathVersion(oldPath)
+	if !ok || strings.HasPrefix(pathMajor, ".") {
+		return fmt.Errorf(
+			"cannot move module path %s to a new major version", oldPath)
+	}
+	major := target.PathMajor()
+	if major == 0 {
+		major = 1
+	}
+	if latest := target.VersionLocal(); latest.Major() != major {
+		return fmt.Errorf(
+			"module %q must be released at v%d before moving to v%d; "+
+				"its latest local tag is %s",
+			target.ShortName(), major, major+1, latest.Pretty())
+	}
+	newPath := fmt.Sprintf("%s/v%d", prefix, major+1)
+	newVersion := semver.New(major+1, 0, 0)
+
+	// So that the change can be reviewed, or undone.
+	if err := git.NewQuiet(mgr.AbsPath(), true).AssureCleanWorkspace(); err != nil {
+		return err
+	}
+	fmt.Printf(
+		"Moving %s from %s to %s, for %s\n",
+		target.ShortName(), oldPath, newPath, newVersion)
+	rw := mgr.importRewriter(target, newPath)
+	var changed []string
+	for _, m := range mgr.modules {
+		files, err := edit.New(m, doIt).RewriteImports(rw)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, files...)
+	}
+	if err := edit.New(target, doIt).SetModulePath(newPath); err != nil {
+		return err
+	}
+	for _, m := range mgr.modules {
+		if m.ShortName() == target.ShortName() {
+			continue
+		}
+		if yes, _ := m.DependsOn(target); !yes {
+			continue
+		}
+		err := edit.New(m, doIt).MoveDependency(target, newPath, newVersion)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Imports rewritten in %d files.\n", len(changed))
+	fmt.Printf(
+		"Next, commit the change, then run 'gorepomod release %s major' "+
+			"to release %s, then pin %s in its dependents.\n",
+		target.ShortName(), newVersion, target.ShortName())
+	return nil
+}
+
+// importRewriter moves imports of the target's packages to the
+// new module path, leaving alone those of nested modules, e.g.
+// sigs.k8s.io/kustomize/kyaml/sub, if that's a module of its own.
+func (mgr *Manager) importRewriter(
+	target misc.LaModule, newPath string) edit.ImportRewriter {
+	oldPath := target.ImportPath()
+	return func(p string) (string, bool) {
+		if !inModulePath(p, oldPath) {
+			return p, false
+		}
+		for _, m := range mgr.modules {
+			if len(m.ImportPath()) > len(oldPath) &&
+				inModulePath(p, m.ImportPath()) {
+				return p, false
+			}
+		}
+		return newPath + p[len(oldPath):], true
+	}
+}
+
+// inModulePath is true if the import path names a
+// package in the module with the module path.
+func inModulePath(importPath, modulePath string) bool {
+	return importPath == modulePath ||
+		strings.HasPrefix(importPath, modulePath+"/")
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/notes"
+)
+
+// changelogFileName is the name of the changelog
+// written to a module's directory.
+const changelogFileName = "CHANGELOG.md"
+
+// collectChanges records in the journal the commits touching the
+// module's files, save those of nested modules, reachable from
+// the commit but not from the previous release's tag.
+func (mgr *Manager) collectChanges(
+	gr *git.Runner, j *journal, commit string) (err error) {
+	m := mgr.modules.Find(misc.ModuleShortName(j.Module))
+	if m == nil {
+		return fmt.Errorf("cannot find module %q", j.Module)
+	}
+	j.Changes, err = gr.Log(j.PreviousTag, commit, mgr.pathSpecs(m))
+	return err
+}
+
+func releaseNotes(changes []git.LogEntry) *notes.Notes {
+	commits := make([]notes.Commit, len(changes))
+	for i, c := range changes {
+		commits[i] = notes.Commit(c)
+	}
+	return notes.New(commits)
+}
+
+// releaseMessage is the message of a release tag,
+// a summary line followed by the release notes.
+func releaseMessage(tag, branch string, changes []git.LogEntry) string {
+	msg := fmt.Sprintf("Release %s on branch %s", tag, branch)
+	if n := releaseNotes(changes); !n.IsEmpty() {
+		msg += "\n\n" + n.Text()
+	}
+	return msg
+}
+
+// updateChangelog adds the release notes of the release
+// in the journal to the module's changelog, and commits it
+// to the branch checked out, i.e. the main branch.
+func (mgr *Manager) updateChangelog(gr *git.Runner, j *journal) error {
+	title := fmt.Sprintf("%s (%s)", j.Version, time.Now().Format("2006-01-02"))
+	section := releaseNotes(j.Changes).Markdown(title)
+	fmt.Printf("Adding to %s:\n%s", j.Changelog, section)
+	if j.dryRun {
+		return nil
+	}
+	p := filepath.Join(mgr.AbsPath(), filepath.FromSlash(j.Changelog))
+	old, err := ioutil.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = ioutil.WriteFile(
+		p, []byte(notes.AddToChangelog(string(old), section)), 0644)
+	if err != nil {
+		return err
+	}
+	return gr.CommitFiles(
+		fmt.Sprintf("Update changelog for %s", j.Tag), j.Changelog)
+}
@@ -0,0 +1,253 @@
+package repo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/work"
+)
+
+// checkResult is the outcome of one preflight command in one module.
+type checkResult struct {
+	module  misc.ModuleShortName
+	command string
+	err     error
+	out     []byte
+}
+
+// preflight runs the configured commands in the target module,
+// using only the dependencies its go.mod file names, and, if
+// configured, in each in-repo module depending on the target,
+// using the target's local code via a temporary go.work file.
+// The modules are those in the checkout of the repository at
+// root, e.g. the repository itself, or a temporary work tree.
+// It prints a summary per module, and fails if any command did.
+func (mgr *Manager) preflight(root string, target misc.LaModule) error {
+	if mgr.cfg.Preflight.Skip {
+		return nil
+	}
+	fmt.Printf("Preflight checks for %s\n", target.ShortName())
+	results := mgr.runChecks(root, target, []string{"GOWORK=off"})
+	if mgr.cfg.Preflight.Dependents {
+		deps, err := mgr.checkDependents(root, target)
+		if err != nil {
+			return err
+		}
+		results = append(results, deps...)
+	}
+	if failed := reportChecks(results); len(failed) > 0 {
+		return fmt.Errorf(
+			"preflight checks failed (%s); release aborted",
+			strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// preflightAt runs the preflight checks against the commit,
+// checked out in a temporary work tree, leaving the repository's
+// own working tree alone.
+func (mgr *Manager) preflightAt(
+	gr *git.Runner, target misc.LaModule, commit string) error {
+	if mgr.cfg.Preflight.Skip {
+		return nil
+	}
+	tmpDir, err := ioutil.TempDir("", "gorepomod-preflight")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	root := filepath.Join(tmpDir, "tree")
+	if err = gr.AddWorktree(root, commit); err != nil {
+		return err
+	}
+	err = mgr.preflight(root, target)
+	if rmErr := gr.RemoveWorktree(root); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// preflightStep runs the preflight checks for the module
+// released in the journal, against the commit, or if that's
+// empty, the working tree.
+func (mgr *Manager) preflightStep(
+	gr *git.Runner, j *journal, commit string) error {
+	m := mgr.modules.Find(misc.ModuleShortName(j.Module))
+	if m == nil {
+		return fmt.Errorf("cannot find module %q", j.Module)
+	}
+	if commit == "" {
+		return mgr.preflight(mgr.AbsPath(), m)
+	}
+	return mgr.preflightAt(gr, m, commit)
+}
+
+// CheckDependents runs the preflight commands in each in-repo
+// module depending on the target, using the target's local code,
+// and prints a compatibility matrix.  It fails if any command did.
+// No go.mod file is changed; each dependent gets a temporary
+// go.work file instead.
+func (mgr *Manager) CheckDependents(target misc.LaModule) error {
+	deps := mgr.modules.GetAllThatDependOn(target)
+	if len(deps) == 0 {
+		fmt.Printf("No module depends on %s.\n", target.ShortName())
+		return nil
+	}
+	fmt.Printf(
+		"Checking %d dependents of %s against its local code\n",
+		len(deps), target.ShortName())
+	results, err := mgr.checkDependents(mgr.AbsPath(), target)
+	if err != nil {
+		return err
+	}
+	if failed := reportChecks(results); len(failed) > 0 {
+		return fmt.Errorf(
+			"dependents of %s fail with its local code (%s)",
+			target.ShortName(), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// checkDependents runs the preflight commands in each in-repo
+// module depending on the target, each with a temporary go.work
+// file using both the dependent and the target, as found in the
+// checkout at root.
+func (mgr *Manager) checkDependents(
+	root string, target misc.LaModule) ([]checkResult, error) {
+	tmpDir, err := ioutil.TempDir("", "gorepomod-check")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	var results []checkResult
+	for i, dep := range mgr.modules.GetAllThatDependOn(target) {
+		dir := filepath.Join(tmpDir, strconv.Itoa(i))
+		if err = os.Mkdir(dir, 0755); err != nil {
+			return nil, err
+		}
+		w, err := work.LoadOrNew(dir)
+		if err != nil {
+			return nil, err
+		}
+		w.Use(moduleAt(root, target), moduleAt(root, dep.M))
+		if err = w.Write(true); err != nil {
+			return nil, err
+		}
+		results = append(results, mgr.runChecks(
+			root, dep.M, workspaceEnv(w.Path()))...)
+	}
+	return results, nil
+}
+
+// workspaceEnv returns the environment for using the go.work
+// file at the path.  Workspaces forbid -mod=mod or -mod=vendor,
+// so such a flag is dropped from GOFLAGS.
+func workspaceEnv(path string) []string {
+	var flags []string
+	for _, f := range strings.Fields(os.Getenv("GOFLAGS")) {
+		if !strings.HasPrefix(f, "-mod=") {
+			flags = append(flags, f)
+		}
+	}
+	return []string{
+		"GOWORK=" + path,
+		"GOFLAGS=" + strings.Join(flags, " "),
+	}
+}
+
+// moduleAt is the module's directory in the checkout at root.
+func moduleAt(root string, m misc.LaModule) string {
+	return filepath.Join(root, filepath.FromSlash(moduleDir(m)))
+}
+
+// runChecks runs the preflight commands in the
+// module, as found in the checkout at root.
+func (mgr *Manager) runChecks(
+	root string, m misc.LaModule, env []string) (results []checkResult) {
+	for _, args := range mgr.cfg.PreflightCommands() {
+		out, err := runCheck(moduleAt(root, m), args, env)
+		results = append(results, checkResult{
+			module:  m.ShortName(),
+			command: strings.Join(args, " "),
+			err:     err,
+			out:     out,
+		})
+	}
+	return
+}
+
+// runCheck runs the command in the directory.  A 'go build'
+// matching a single main package writes an executable, which
+// would dirty the workspace, so unless the command says where
+// it goes, it's discarded.
+func runCheck(dir string, args, env []string) ([]byte, error) {
+	if len(args) > 1 && args[0] == "go" && args[1] == "build" &&
+		!hasOutputFlag(args) {
+		args = append([]string{"go", "build", "-o", os.DevNull}, args[2:]...)
+	}
+	c := exec.Command(args[0], args[1:]...)
+	c.Dir = dir
+	c.Env = append(os.Environ(), env...)
+	return c.CombinedOutput()
+}
+
+func hasOutputFlag(args []string) bool {
+	for _, a := range args {
+		if a == "-o" || strings.HasPrefix(a, "-o=") {
+			return true
+		}
+	}
+	return false
+}
+
+// reportChecks prints a matrix of the results, with a row per
+// module and a column per command, followed by the output of the
+// failed commands.  It returns a description of each failure.
+func reportChecks(results []checkResult) (failed []string) {
+	var modules []misc.ModuleShortName
+	var commands []string
+	status := make(map[misc.ModuleShortName]map[string]string)
+	longest := len("MODULE")
+	for _, r := range results {
+		if _, ok := status[r.module]; !ok {
+			modules = append(modules, r.module)
+			status[r.module] = make(map[string]string)
+			if l := len(r.module); l > longest {
+				longest = l
+			}
+		}
+		if len(modules) == 1 {
+			commands = append(commands, r.command)
+		}
+		status[r.module][r.command] = "ok"
+		if r.err != nil {
+			status[r.module][r.command] = "FAILED"
+			failed = append(failed, fmt.Sprintf("%s: %s", r.module, r.command))
+		}
+	}
+	row := func(m string, cells func(c string) string) {
+		line := fmt.Sprintf("  %-"+strconv.Itoa(longest+2)+"s", m)
+		for _, c := range commands {
+			line += fmt.Sprintf("%-"+strconv.Itoa(len(c)+2)+"s", cells(c))
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	row("MODULE", func(c string) string { return c })
+	for _, m := range modules {
+		row(string(m), func(c string) string { return status[m][c] })
+	}
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Printf(
+				"\nin %s, %s: %v\n%s", r.module, r.command, r.err, r.out)
+		}
+	}
+	return
+}
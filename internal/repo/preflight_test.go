@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/config"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+func TestReportChecks(t *testing.T) {
+	var testCases = map[string]struct {
+		results  []checkResult
+		expected []string
+	}{
+		"none": {},
+		"allOk": {
+			results: []checkResult{
+				{module: "api", command: "go build ./..."},
+				{module: "api", command: "go test ./..."},
+			},
+		},
+		"someFailed": {
+			results: []checkResult{
+				{module: "api", command: "go build ./..."},
+				{module: "api", command: "go test ./...", err: fmt.Errorf("exit status 1")},
+				{module: "kustomize", command: "go build ./...", err: fmt.Errorf("exit status 2")},
+				{module: "kustomize", command: "go test ./..."},
+			},
+			expected: []string{
+				"api: go test ./...",
+				"kustomize: go build ./...",
+			},
+		},
+	}
+	for n, tc := range testCases {
+		if actual := reportChecks(tc.results); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", n, tc.expected, actual)
+		}
+	}
+}
+
+func TestHasOutputFlag(t *testing.T) {
+	var testCases = map[string]struct {
+		args     []string
+		expected bool
+	}{
+		"none":   {args: []string{"go", "build", "./..."}, expected: false},
+		"spaced": {args: []string{"go", "build", "-o", "bin/", "./..."}, expected: true},
+		"joined": {args: []string{"go", "build", "-o=bin/", "./..."}, expected: true},
+	}
+	for n, tc := range testCases {
+		if actual := hasOutputFlag(tc.args); actual != tc.expected {
+			t.Errorf("%s: expected %v, got %v", n, tc.expected, actual)
+		}
+	}
+}
+
+// The checks run against what's tagged: the remote main
+// branch, which has a file the local main branch doesn't.
+func TestPreflightChecksReleasedCode(t *testing.T) {
+	var testCases = map[string]struct {
+		strategy misc.ReleaseStrategy
+		command  string
+		released bool
+	}{
+		"branch": {
+			strategy: misc.BranchPerMinor,
+			command:  "test -f marker",
+			released: true,
+		},
+		"branchFails": {
+			strategy: misc.BranchPerMinor,
+			command:  "test ! -f marker",
+		},
+		"trunk": {
+			strategy: misc.Trunk,
+			command:  "test -f marker",
+			released: true,
+		},
+		"trunkFails": {
+			strategy: misc.Trunk,
+			command:  "test ! -f marker",
+		},
+	}
+	for n, tc := range testCases {
+		r := newTestRepo(t)
+		r.write("kyaml/marker", "")
+		r.commit("Add marker")
+		r.git("push", "-q", "origin", "main")
+		r.git("reset", "-q", "--hard", "HEAD~1")
+		mgr := r.manager(tc.strategy)
+		mgr.cfg.Preflight = config.Preflight{Commands: []string{tc.command}}
+		err := mgr.Release(mgr.FindModule("kyaml"), semver.Minor, nil, true)
+		if tc.released {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), "preflight") {
+			t.Errorf("%s: expected preflight failure, got %v", n, err)
+		}
+		if r.remoteHasRef("refs/tags/kyaml/v0.2.0") != tc.released {
+			t.Errorf("%s: expected tag pushed %v", n, tc.released)
+		}
+		if actual := r.git("worktree", "list"); strings.Contains(actual, "\n") {
+			t.Errorf("%s: expected no extra work trees, got\n%s", n, actual)
+		}
+		r.close()
+	}
+}
+
+func TestWorkspaceEnv(t *testing.T) {
+	var testCases = map[string]struct {
+		goFlags  string
+		expected []string
+	}{
+		"none": {
+			expected: []string{"GOWORK=/r/go.work", "GOFLAGS="},
+		},
+		"modDropped": {
+			goFlags:  "-mod=mod -v",
+			expected: []string{"GOWORK=/r/go.work", "GOFLAGS=-v"},
+		},
+	}
+	old := os.Getenv("GOFLAGS")
+	defer os.Setenv("GOFLAGS", old)
+	for n, tc := range testCases {
+		os.Setenv("GOFLAGS", tc.goFlags)
+		actual := workspaceEnv("/r/go.work")
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", n, tc.expected, actual)
+		}
+	}
+}
+
+// The dependent api requires kyaml v0.1.0, but uses Walk,
+// which only kyaml's unreleased code has.
+func TestCheckDependents(t *testing.T) {
+	var testCases = map[string]struct {
+		call   string
+		passes bool
+	}{
+		"unreleasedCode": {
+			call:   "kyaml.Walk()",
+			passes: true,
+		},
+		"missingCode": {
+			call: "kyaml.Run()",
+		},
+	}
+	for n, tc := range testCases {
+		r := newTestRepo(t)
+		r.write("api/go.mod", "module "+testRepoPath+"/api\n\ngo 1.15\n\n"+
+			"require "+testRepoPath+"/kyaml v0.1.0\n")
+		r.write("api/api.go", "package api\n\nimport \""+testRepoPath+
+			"/kyaml\"\n\nfunc Walk() { "+tc.call+" }\n")
+		r.commit("Add api")
+		mgr := r.manager(misc.BranchPerMinor)
+		mgr.cfg.Preflight = config.Preflight{Commands: []string{"go build ./..."}}
+		// Without a workspace, the released kyaml is needed.
+		_, err := runCheck(filepath.Join(r.dir, "api"),
+			[]string{"go", "build", "./..."}, []string{"GOPROXY=off"})
+		if err == nil {
+			t.Errorf("%s: expected api not to build on its own", n)
+		}
+		err = mgr.CheckDependents(mgr.FindModule("kyaml"))
+		if tc.passes {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", n, err)
+			}
+		} else if err == nil ||
+			!strings.Contains(err.Error(), "fail with its local code") {
+			t.Errorf("%s: expected the check to fail, got %v", n, err)
+		}
+		if actual := r.git("status", "--porcelain"); actual != "" {
+			t.Errorf("%s: expected a clean workspace, got\n%s", n, actual)
+		}
+		r.close()
+	}
+}
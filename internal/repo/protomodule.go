@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
+	"github.com/monopole/gorepomod/internal/config"
 	"github.com/monopole/gorepomod/internal/misc"
 	"github.com/monopole/gorepomod/internal/utils"
 	"golang.org/x/mod/modfile"
@@ -30,10 +32,19 @@ func (pm *protoModule) PathToGoMod() string {
 	return pm.pathToGoMod
 }
 
-// Represents the trailing version label in a module name.
+// Represents the trailing major version suffix in a module
+// name, i.e. v2 or above.
 // See https://blog.golang.org/v2-go-modules
-var trailingVersionPattern = regexp.MustCompile("/v\\d+$")
+var trailingVersionPattern = regexp.MustCompile("(^|/)v([2-9]|[1-9][0-9]+)$")
 
+// ShortName is the module's name relative to the repository.
+//
+// A module path's major version suffix, e.g. the v2 in
+// kyaml/v2, is dropped if the module's directory is kyaml (the
+// "major branch" layout), but kept if the directory is kyaml/v2
+// (the "major subdirectory" layout), so that the name differs
+// from that of the module in kyaml, and remains the path to
+// the module's directory.
 func (pm *protoModule) ShortName(
 	repoImportPath string) misc.ModuleShortName {
 	fp := pm.FullPath()
@@ -41,8 +52,16 @@ func (pm *protoModule) ShortName(
 		return misc.ModuleAtTop
 	}
 	p := fp[len(repoImportPath)+1:]
-	stripped := trailingVersionPattern.ReplaceAllString(p, "")
-	return misc.ModuleShortName(stripped)
+	suffix := trailingVersionPattern.FindString(p)
+	if suffix == "" ||
+		filepath.Base(pm.pathToGoMod) == strings.TrimPrefix(suffix, "/") {
+		return misc.ModuleShortName(p)
+	}
+	if stripped := p[:len(p)-len(suffix)]; stripped != "" {
+		return misc.ModuleShortName(stripped)
+	}
+	// The top module, at a major version above one.
+	return misc.ModuleAtTop
 }
 
 func loadProtoModules(
@@ -78,7 +97,6 @@ func loadProtoModule(path string) (*protoModule, error) {
 
 func getPathsToModules(
 	repoRoot string, exclusions []string) (result []string, err error) {
-	exclusionMap := utils.SliceToSet(exclusions)
 	err = filepath.Walk(
 		repoRoot,
 		func(path string, info os.FileInfo, err error) error {
@@ -86,16 +104,35 @@ func getPathsToModules(
 				return fmt.Errorf("trouble at pathToGoMod %q: %v\n", path, err)
 			}
 			if info.IsDir() {
-				if _, ok := exclusionMap[info.Name()]; ok {
+				rel, err := filepath.Rel(repoRoot, path)
+				if err != nil {
+					return err
+				}
+				if rel == dotDir {
+					return nil
+				}
+				if config.IsExcluded(exclusions, filepath.ToSlash(rel)) {
+					return filepath.SkipDir
+				}
+				// Below a module other than the top one, only a major
+				// version subdirectory may hold another module, e.g.
+				// kyaml/v2 below kyaml.
+				if parent := filepath.Dir(path); parent != repoRoot &&
+					isModuleDir(parent) &&
+					!(trailingVersionPattern.MatchString(info.Name()) &&
+						isModuleDir(path)) {
 					return filepath.SkipDir
 				}
 				return nil
 			}
 			if info.Name() == goModFile {
 				result = append(result, path[:len(path)-len(goModFile)-1])
-				return filepath.SkipDir
 			}
 			return nil
 		})
 	return
 }
+
+func isModuleDir(path string) bool {
+	return utils.PathExists(filepath.Join(path, goModFile))
+}
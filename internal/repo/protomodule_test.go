@@ -1,6 +1,11 @@
 package repo
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/monopole/gorepomod/internal/misc"
@@ -11,6 +16,7 @@ import (
 func TestShortName(t *testing.T) {
 	var testCases = map[string]struct {
 		name    misc.ModuleShortName
+		dir     string
 		modFile *modfile.File
 	}{
 		"one": {
@@ -35,9 +41,58 @@ func TestShortName(t *testing.T) {
 				},
 			},
 		},
+		"majorBranch": {
+			name: misc.ModuleShortName("kyaml"),
+			dir:  "/src/gh.com/micheal/kyaml",
+			modFile: &modfile.File{
+				Module: &modfile.Module{
+					Mod: module.Version{Path: "gh.com/micheal/kyaml/v2"},
+				},
+			},
+		},
+		"majorSubdirectory": {
+			name: misc.ModuleShortName("kyaml/v2"),
+			dir:  "/src/gh.com/micheal/kyaml/v2",
+			modFile: &modfile.File{
+				Module: &modfile.Module{
+					Mod: module.Version{Path: "gh.com/micheal/kyaml/v2"},
+				},
+			},
+		},
+		"topMajorBranch": {
+			name: misc.ModuleAtTop,
+			dir:  "/src/gh.com/micheal",
+			modFile: &modfile.File{
+				Module: &modfile.Module{
+					Mod: module.Version{Path: "gh.com/micheal/v3"},
+				},
+			},
+		},
+		"topMajorSubdirectory": {
+			name: misc.ModuleShortName("v3"),
+			dir:  "/src/gh.com/micheal/v3",
+			modFile: &modfile.File{
+				Module: &modfile.Module{
+					Mod: module.Version{Path: "gh.com/micheal/v3"},
+				},
+			},
+		},
+		"notMajor": {
+			name: misc.ModuleShortName("api/v1"),
+			dir:  "/src/gh.com/micheal/api/v1",
+			modFile: &modfile.File{
+				Module: &modfile.Module{
+					Mod: module.Version{Path: "gh.com/micheal/api/v1"},
+				},
+			},
+		},
 	}
 	for n, tc := range testCases {
-		m := protoModule{pathToGoMod: "irrelevant", mf: tc.modFile}
+		dir := tc.dir
+		if dir == "" {
+			dir = "irrelevant"
+		}
+		m := protoModule{pathToGoMod: dir, mf: tc.modFile}
 		actual := m.ShortName("gh.com/micheal")
 		if actual != tc.name {
 			t.Errorf(
@@ -45,3 +100,91 @@ func TestShortName(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPathsToModules(t *testing.T) {
+	var testCases = map[string]struct {
+		files      []string
+		exclusions []string
+		expected   []string
+	}{
+		"nested": {
+			files: []string{
+				"api/go.mod",
+				"api/internal/go.mod",
+				"kyaml/go.mod",
+				"kyaml/v2/go.mod",
+				"kyaml/v2/testdata/go.mod",
+				"kyaml/v3/notmod.go",
+				"kyaml/v3/sub/go.mod",
+				"kyaml/testdata/go.mod",
+				"kyaml/vendor/go.mod",
+			},
+			expected: []string{"api", "kyaml", "kyaml/v2"},
+		},
+		"topModule": {
+			files: []string{
+				"go.mod",
+				"api/go.mod",
+				"api/internal/go.mod",
+				"api/v2/go.mod",
+				"pkg/util.go",
+				"v2/go.mod",
+			},
+			expected: []string{".", "api", "api/v2", "v2"},
+		},
+		"noTopModule": {
+			files: []string{
+				"api/go.mod",
+				"cmd/config/go.mod",
+				"testdata/go.mod",
+				"hack/go.mod",
+			},
+			expected: []string{"api", "cmd/config", "testdata"},
+		},
+		"excluded": {
+			files: []string{
+				"api/go.mod",
+				"testdata/go.mod",
+				"plugin/a/go.mod",
+				"plugin/b/go.mod",
+			},
+			exclusions: []string{"testdata", "plugin/b"},
+			expected:   []string{"api", "plugin/a"},
+		},
+	}
+	for n, tc := range testCases {
+		root, err := ioutil.TempDir("", "modules")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range tc.files {
+			p := filepath.Join(root, filepath.FromSlash(f))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		paths, err := getPathsToModules(
+			root, append([]string{"hack"}, tc.exclusions...))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", n, err)
+		}
+		var actual []string
+		for _, p := range paths {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, filepath.ToSlash(rel))
+		}
+		// The walk finds modules in lexical order of their files.
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf(
+				"%s: expected %v, got %v", n, tc.expected, actual)
+		}
+		os.RemoveAll(root)
+	}
+}
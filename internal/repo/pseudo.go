@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/monopole/gorepomod/internal/edit"
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+	"github.com/monopole/gorepomod/internal/utils"
+)
+
+// nearestTag returns the earliest local version of the target
+// whose tag contains the commit named by the pseudo-version,
+// i.e. the first release having the commit's changes.
+func (mgr *Manager) nearestTag(
+	gr *git.Runner, target misc.LaModule,
+	v semver.SemVer) (semver.SemVer, error) {
+	rev := v.Revision()
+	if rev == "" {
+		return semver.Zero(), fmt.Errorf("%s isn't a pseudo-version", v)
+	}
+	tags, err := gr.TagsContaining(rev)
+	if err != nil {
+		return semver.Zero(), err
+	}
+	contains := utils.SliceToSet(tags)
+	versions := mgr.versionsLocal[target.ShortName()]
+	// Versions are sorted newest first.
+	for i := len(versions) - 1; i >= 0; i-- {
+		tag, err := mgr.tag(target, versions[i])
+		if err != nil {
+			return semver.Zero(), err
+		}
+		if contains[tag] {
+			return versions[i], nil
+		}
+	}
+	return semver.Zero(), fmt.Errorf(
+		"no tag of %q contains commit %s", target.ShortName(), rev)
+}
+
+// pseudoWarnings describes the module's in-repo dependencies
+// pinned to an untagged commit (by a pseudo-version) rather
+// than a release, naming the release to pin to instead.
+func (mgr *Manager) pseudoWarnings(
+	gr *git.Runner, m misc.LaModule) (result []string) {
+	for _, dep := range mgr.modules.InternalDeps(m) {
+		if !dep.V.IsPseudo() || m.IsUnPinned(dep.M) {
+			continue
+		}
+		w := fmt.Sprintf(
+			"requires %s at untagged commit %s (%s)",
+			dep.M.ShortName(), dep.V.Revision(), dep.V)
+		if v, err := mgr.nearestTag(gr, dep.M, dep.V); err != nil {
+			w += "; " + err.Error()
+		} else {
+			w += fmt.Sprintf("; first released in %s", v)
+		}
+		result = append(result, w)
+	}
+	return
+}
+
+// PinToNearestTags pins each module depending on the target
+// at a pseudo-version to the nearest following release,
+// leaving alone those depending on a release (or replacing
+// the target with a local path).
+func (mgr *Manager) PinToNearestTags(
+	doIt bool, target misc.LaModule) error {
+	gr := git.NewQuiet(mgr.AbsPath(), true)
+	pinned := 0
+	err := mgr.modules.Apply(func(m misc.LaModule) error {
+		yes, v := m.DependsOn(target)
+		if !yes || !v.IsPseudo() || m.IsUnPinned(target) {
+			return nil
+		}
+		newV, err := mgr.nearestTag(gr, target, v)
+		if err != nil {
+			return fmt.Errorf("cannot pin %q: %v", m.ShortName(), err)
+		}
+		fmt.Printf("pinning %s in %s: %s => %s\n",
+			target.ShortName(), m.ShortName(), v, newV)
+		pinned++
+		return edit.New(m, doIt).Pin(target, newV)
+	})
+	if err != nil {
+		return err
+	}
+	if pinned == 0 {
+		fmt.Printf(
+			"no module requires %s at a pseudo-version\n", target.ShortName())
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+func TestNearestTag(t *testing.T) {
+	var testCases = map[string]struct {
+		// Tags made, in order, at HEAD and then at new commits.
+		tags     []string
+		expected string
+		err      string
+	}{
+		"laterTag": {
+			tags:     []string{"kyaml/v0.2.0", "kyaml/v0.3.0"},
+			expected: "v0.2.0",
+		},
+		"noLaterTag": {
+			err: `no tag of "kyaml" contains commit `,
+		},
+	}
+	for n, tc := range testCases {
+		r := newTestRepo(t)
+		// The untagged "feat: add Walk" commit.
+		rev := r.git("rev-parse", "--short=12", "HEAD")
+		for i, tag := range tc.tags {
+			if i > 0 {
+				r.write("kyaml/"+tag[len("kyaml/"):]+".go", "package kyaml\n")
+				r.commit("feat: " + tag)
+			}
+			r.git("tag", "-a", "-m", "Release "+tag, tag)
+		}
+		pseudo, err := semver.Parse("v0.1.1-0.20200101000000-" + rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mgr := r.manager(misc.Trunk)
+		actual, err := mgr.nearestTag(
+			git.NewQuiet(r.dir, true), mgr.FindModule("kyaml"), pseudo)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err+rev {
+				t.Errorf("%s: expected error %q, got %v", n, tc.err+rev, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", n, err)
+		} else if actual.String() != tc.expected {
+			t.Errorf("%s: expected %s, got %s", n, tc.expected, actual)
+		}
+		r.close()
+	}
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"golang.org/x/mod/module"
+)
+
+// inferRepoPath guesses the import path of the repository in the
+// given directory, first from the paths of the modules in it,
+// then from the URL of its git remote.
+func inferRepoPath(repoRoot string) (string, error) {
+	paths, err := getPathsToModules(repoRoot, []string{dotGitFileName})
+	if err != nil {
+		return "", err
+	}
+	for _, p := range paths {
+		pm, err := loadProtoModule(p)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(repoRoot, p)
+		if err != nil {
+			return "", err
+		}
+		if rp, ok := repoPathFromModule(
+			pm.FullPath(), filepath.ToSlash(rel)); ok {
+			return rp, nil
+		}
+	}
+	runner := git.NewQuiet(repoRoot, true)
+	if remote, err := runner.DetermineRemoteToUse(""); err == nil {
+		if u, err := runner.RemoteURL(remote); err == nil {
+			if rp, ok := repoPathFromURL(u); ok {
+				return rp, nil
+			}
+		}
+	}
+	return "", fmt.Errorf(
+		"unable to determine the import path of the repository in %q; "+
+			"specify it with --repoPath", repoRoot)
+}
+
+// repoPathFromModule derives a repository's import path from the
+// path of a module found in the directory rel (a slash separated
+// path relative to the repository root).
+func repoPathFromModule(modPath, rel string) (string, bool) {
+	prefix, _, ok := module.SplitPathVersion(modPath)
+	if !ok {
+		return "", false
+	}
+	if rel == "." {
+		return prefix, true
+	}
+	// The module might be in a major version subdirectory,
+	// e.g. module foo/v2 in directory foo/v2, or not.
+	for _, p := range []string{modPath, prefix} {
+		if strings.HasSuffix(p, "/"+rel) {
+			return strings.TrimSuffix(p, "/"+rel), true
+		}
+	}
+	return "", false
+}
+
+// Matches scp-like git URLs, e.g. git@github.com:monopole/gorepomod
+var scpLikeUrl = regexp.MustCompile(`^[\w.-]+@([\w.-]+):(.+)$`)
+
+// repoPathFromURL derives a repository's import path from
+// the URL of its git remote.
+func repoPathFromURL(raw string) (string, bool) {
+	raw = strings.TrimSuffix(strings.TrimSpace(raw), "/")
+	raw = strings.TrimSuffix(raw, ".git")
+	if m := scpLikeUrl.FindStringSubmatch(raw); m != nil {
+		return m[1] + "/" + strings.TrimPrefix(m[2], "/"), true
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" || u.Path == "" {
+		return "", false
+	}
+	return u.Hostname() + u.Path, true
+}
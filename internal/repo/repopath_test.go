@@ -0,0 +1,82 @@
+package repo
+
+import "testing"
+
+func TestRepoPathFromModule(t *testing.T) {
+	var testCases = map[string]struct {
+		modPath  string
+		rel      string
+		expected string
+	}{
+		"top": {
+			modPath:  "github.com/monopole/gorepomod",
+			rel:      ".",
+			expected: "github.com/monopole/gorepomod",
+		},
+		"top major": {
+			modPath:  "github.com/monopole/gorepomod/v2",
+			rel:      ".",
+			expected: "github.com/monopole/gorepomod",
+		},
+		"nested": {
+			modPath:  "sigs.k8s.io/kustomize/cmd/config",
+			rel:      "cmd/config",
+			expected: "sigs.k8s.io/kustomize",
+		},
+		"nested major": {
+			modPath:  "sigs.k8s.io/kustomize/kustomize/v3",
+			rel:      "kustomize",
+			expected: "sigs.k8s.io/kustomize",
+		},
+		"major subdirectory": {
+			modPath:  "sigs.k8s.io/kustomize/kyaml/v2",
+			rel:      "kyaml/v2",
+			expected: "sigs.k8s.io/kustomize",
+		},
+		"mismatch": {
+			modPath:  "example.com/whatever",
+			rel:      "examples/whatever",
+			expected: "",
+		},
+	}
+	for n, tc := range testCases {
+		actual, ok := repoPathFromModule(tc.modPath, tc.rel)
+		if actual != tc.expected || ok != (tc.expected != "") {
+			t.Errorf("%s: expected %q, got %q", n, tc.expected, actual)
+		}
+	}
+}
+
+func TestRepoPathFromURL(t *testing.T) {
+	var testCases = map[string]struct {
+		url      string
+		expected string
+	}{
+		"https": {
+			url:      "https://github.com/monopole/gorepomod.git",
+			expected: "github.com/monopole/gorepomod",
+		},
+		"https no suffix": {
+			url:      "https://github.com/monopole/gorepomod",
+			expected: "github.com/monopole/gorepomod",
+		},
+		"scp": {
+			url:      "git@github.com:monopole/gorepomod.git",
+			expected: "github.com/monopole/gorepomod",
+		},
+		"ssh": {
+			url:      "ssh://git@github.com:22/monopole/gorepomod.git\n",
+			expected: "github.com/monopole/gorepomod",
+		},
+		"local": {
+			url:      "/tmp/remote.git",
+			expected: "",
+		},
+	}
+	for n, tc := range testCases {
+		actual, ok := repoPathFromURL(tc.url)
+		if actual != tc.expected || ok != (tc.expected != "") {
+			t.Errorf("%s: expected %q, got %q", n, tc.expected, actual)
+		}
+	}
+}
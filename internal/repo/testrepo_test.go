@@ -0,0 +1,216 @@
+package repo
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/config"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/naming"
+)
+
+// testRepo is a clone, with a bare remote "origin", of a
+// repository whose main branch holds the module kyaml,
+// tagged kyaml/v0.1.0, all in a temporary directory.
+type testRepo struct {
+	t      *testing.T
+	tmp    string
+	dir    string
+	remote string
+	// env holds the environment variables to restore on close.
+	env map[string]string
+}
+
+const testRepoPath = "example.com/multi"
+
+func newTestRepo(t *testing.T) *testRepo {
+	tmp, err := ioutil.TempDir("", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &testRepo{
+		t:      t,
+		tmp:    tmp,
+		dir:    filepath.Join(tmp, "multi"),
+		remote: filepath.Join(tmp, "remote.git"),
+	}
+	r.run(tmp, "git", "init", "-q", "--bare", r.remote)
+	r.run(r.remote, "git", "symbolic-ref", "HEAD", "refs/heads/main")
+	r.run(tmp, "git", "init", "-q", r.dir)
+	r.git("symbolic-ref", "HEAD", "refs/heads/main")
+	r.git("config", "user.name", "Test")
+	r.git("config", "user.email", "test@example.com")
+	r.git("remote", "add", "origin", r.remote)
+	r.write("kyaml/go.mod", "module "+testRepoPath+"/kyaml\n\ngo 1.15\n")
+	r.write("kyaml/kyaml.go", "package kyaml\n")
+	r.commit("Add kyaml")
+	r.git("tag", "-a", "-m", "Release kyaml/v0.1.0", "kyaml/v0.1.0")
+	r.write("kyaml/walk.go", "package kyaml\n\nfunc Walk() {}\n")
+	r.commit("feat: add Walk")
+	r.git("push", "-q", "origin", "main", "kyaml/v0.1.0")
+	return r
+}
+
+func (r *testRepo) close() {
+	if r.env != nil {
+		// The module cache is read-only.
+		exec.Command("go", "clean", "-modcache").Run()
+		for k, v := range r.env {
+			os.Setenv(k, v)
+		}
+	}
+	os.RemoveAll(r.tmp)
+}
+
+// serve makes the module's current files available from a
+// module proxy, at the version, to go commands run until
+// close, as if the version were released and published.
+func (r *testRepo) serve(name, version string) {
+	if r.env == nil {
+		r.env = make(map[string]string)
+		for k, v := range map[string]string{
+			"GOPROXY":    "file://" + filepath.Join(r.tmp, "proxy"),
+			"GOSUMDB":    "off",
+			"GOMODCACHE": filepath.Join(r.tmp, "modcache"),
+		} {
+			r.env[k] = os.Getenv(k)
+			os.Setenv(k, v)
+		}
+	}
+	path := testRepoPath + "/" + name
+	dir := filepath.Join(r.tmp, "proxy", filepath.FromSlash(path), "@v")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(filepath.Join(r.dir, name))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	zf, err := os.Create(filepath.Join(dir, version+".zip"))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	defer zf.Close()
+	z := zip.NewWriter(zf)
+	for _, f := range files {
+		content, err := ioutil.ReadFile(filepath.Join(r.dir, name, f.Name()))
+		if err != nil {
+			r.t.Fatal(err)
+		}
+		w, err := z.Create(path + "@" + version + "/" + f.Name())
+		if err != nil {
+			r.t.Fatal(err)
+		}
+		if _, err := w.Write(content); err != nil {
+			r.t.Fatal(err)
+		}
+		if f.Name() == goModFile {
+			err = ioutil.WriteFile(
+				filepath.Join(dir, version+".mod"), content, 0644)
+			if err != nil {
+				r.t.Fatal(err)
+			}
+		}
+	}
+	if err := z.Close(); err != nil {
+		r.t.Fatal(err)
+	}
+	for f, content := range map[string]string{
+		"list":            version + "\n",
+		version + ".info": `{"Version":"` + version + `"}`,
+	} {
+		if err := ioutil.WriteFile(
+			filepath.Join(dir, f), []byte(content), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+}
+
+func (r *testRepo) run(dir string, args ...string) string {
+	c := exec.Command(args[0], args[1:]...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("%v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// git runs git in the clone.
+func (r *testRepo) git(args ...string) string {
+	return r.run(r.dir, append([]string{"git"}, args...)...)
+}
+
+// remoteGit runs git in the remote.
+func (r *testRepo) remoteGit(args ...string) string {
+	return r.run(r.remote, append([]string{"git"}, args...)...)
+}
+
+func (r *testRepo) write(name, content string) {
+	p := filepath.Join(r.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) commit(msg string) {
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", msg)
+}
+
+// hasRef is true if the clone has the ref, e.g. refs/tags/kyaml/v0.2.0.
+func (r *testRepo) hasRef(ref string) bool {
+	return exec.Command(
+		"git", "-C", r.dir, "rev-parse", "--verify", "--quiet", ref).Run() == nil
+}
+
+// remoteHasRef is true if the remote has the ref.
+func (r *testRepo) remoteHasRef(ref string) bool {
+	return exec.Command(
+		"git", "-C", r.remote, "rev-parse", "--verify", "--quiet", ref).Run() == nil
+}
+
+// refuse makes the remote refuse pushes to refs with the prefix,
+// e.g. "refs/tags/", until allow is called.
+func (r *testRepo) refuse(prefix string) {
+	hook := "#!/bin/sh\n" +
+		"while read old new ref; do\n" +
+		"  case \"$ref\" in " + prefix + "*) exit 1;; esac\n" +
+		"done\n"
+	p := filepath.Join(r.remote, "hooks", "pre-receive")
+	if err := ioutil.WriteFile(p, []byte(hook), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) allow() {
+	os.Remove(filepath.Join(r.remote, "hooks", "pre-receive"))
+}
+
+// manager loads the repository, as gorepomod would
+// with the given strategy and no preflight checks.
+func (r *testRepo) manager(strategy misc.ReleaseStrategy) *Manager {
+	dg, err := NewDotGitDataFromPath(r.dir, testRepoPath)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	names, err := naming.New("", "")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	pr, err := dg.NewRepoFactory(nil, "origin", "main", names)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Preflight.Skip = true
+	return pr.NewRepoManager(cfg, strategy)
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/monopole/gorepomod/internal/git"
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/semver"
+)
+
+// signing says how release tags are signed.
+func (mgr *Manager) signing() git.Signing {
+	return git.Signing{
+		Format: mgr.cfg.Signing.Format,
+		Key:    mgr.cfg.Signing.Key,
+	}
+}
+
+// Verify checks the signatures of the module's local tags,
+// either that of the given version, or if it's zero, all of
+// them.  It fails if any tag is unsigned or badly signed.
+func (mgr *Manager) Verify(target misc.LaModule, v semver.SemVer) error {
+	versions := mgr.versionsLocal[target.ShortName()]
+	if !v.IsZero() {
+		found := false
+		for _, lv := range versions {
+			if lv.Equals(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf(
+				"module %q has no local tag for version %s",
+				target.ShortName(), v)
+		}
+		versions = semver.Versions{v}
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("module %q has no local tags", target.ShortName())
+	}
+	gr := git.NewQuiet(mgr.AbsPath(), true)
+	var bad []string
+	for _, lv := range versions {
+		tag, err := mgr.tag(target, lv)
+		if err != nil {
+			return err
+		}
+		desc, err := gr.VerifyTag(tag)
+		if err != nil {
+			bad = append(bad, tag)
+			fmt.Printf("  %-30s FAILED  %v\n", tag, err)
+			continue
+		}
+		fmt.Printf("  %-30s ok      %s\n", tag, desc)
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf(
+			"bad or missing signatures on %s", strings.Join(bad, ", "))
+	}
+	return nil
+}
@@ -4,13 +4,21 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"golang.org/x/mod/module"
 )
 
+const rcPrefix = "rc."
+
 // SemVer is the immutable semantic version per https://semver.org
 type SemVer struct {
 	major int
 	minor int
 	patch int
+	// Dot separated pre-release identifiers, e.g. "rc.1".
+	pre string
+	// Dot separated build metadata identifiers, e.g. "build.5".
+	build string
 }
 
 func New(major, minor, patch int) SemVer {
@@ -42,8 +50,24 @@ func Parse(raw string) (SemVer, error) {
 	if raw[0] != 'v' {
 		return zero, fmt.Errorf("%q must start with letter 'v'", raw)
 	}
-	fields := strings.Split(raw[1:], ".")
-	if len(fields) < 3 {
+	core := raw[1:]
+	var pre, build string
+	if i := strings.Index(core, "+"); i >= 0 {
+		build = core[i+1:]
+		core = core[:i]
+		if err := checkIdentifiers(build, false); err != nil {
+			return zero, fmt.Errorf("%q has bad build metadata; %v", raw, err)
+		}
+	}
+	if i := strings.Index(core, "-"); i >= 0 {
+		pre = core[i+1:]
+		core = core[:i]
+		if err := checkIdentifiers(pre, true); err != nil {
+			return zero, fmt.Errorf("%q has bad pre-release; %v", raw, err)
+		}
+	}
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
 		return zero, fmt.Errorf("%q doesn't have the form v1.2.3", raw)
 	}
 	n := make([]int, 3)
@@ -53,27 +77,160 @@ func Parse(raw string) (SemVer, error) {
 		if err != nil {
 			return zero, err
 		}
+		if n[i] < 0 || hasLeadingZero(fields[i]) {
+			return zero, fmt.Errorf("%q has a malformed number", raw)
+		}
+	}
+	v := New(n[0], n[1], n[2])
+	v.pre = pre
+	v.build = build
+	return v, nil
+}
+
+// ParseLine parses a release line, i.e. a major and minor
+// version like "v1.2", returning v1.2.0.
+func ParseLine(raw string) (SemVer, error) {
+	v, err := Parse(raw + ".0")
+	if err != nil || v.pre != "" || v.build != "" {
+		return zero, fmt.Errorf("%q doesn't have the form v1.2", raw)
+	}
+	return v, nil
+}
+
+// OnLine is true if the version has the major
+// and minor version of the given line.
+func (v SemVer) OnLine(line SemVer) bool {
+	return v.major == line.major && v.minor == line.minor
+}
+
+// LatestOnLine returns the greatest of the (sorted) versions
+// on the given line, and false if there is none.
+func (v Versions) LatestOnLine(line SemVer) (SemVer, bool) {
+	for _, x := range v {
+		if x.OnLine(line) {
+			return x, true
+		}
+	}
+	return zero, false
+}
+
+// checkIdentifiers checks dot separated identifiers, which must be
+// non-empty and hold only ASCII alphanumerics and hyphens.
+// Pre-release identifiers that are numeric cannot have leading zeros.
+func checkIdentifiers(s string, isPre bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("empty identifier")
+		}
+		for _, c := range id {
+			if !(c == '-' ||
+				(c >= '0' && c <= '9') ||
+				(c >= 'a' && c <= 'z') ||
+				(c >= 'A' && c <= 'Z')) {
+				return fmt.Errorf("identifier %q has bad character %q", id, c)
+			}
+		}
+		if isPre && isNumeric(id) && hasLeadingZero(id) {
+			return fmt.Errorf("numeric identifier %q has a leading zero", id)
+		}
 	}
-	return New(n[0], n[1], n[2]), nil
+	return nil
+}
+
+func isNumeric(id string) bool {
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return id != ""
+}
+
+func hasLeadingZero(n string) bool {
+	return len(n) > 1 && n[0] == '0'
+}
+
+func (v SemVer) withPre(pre string) SemVer {
+	r := New(v.major, v.minor, v.patch)
+	r.pre = pre
+	return r
 }
 
 func (v SemVer) Bump(b SvBump) SemVer {
+	// Bumping a pre-release to the version it precedes
+	// finalizes it, e.g. a minor bump of v1.3.0-rc.2 is v1.3.0.
 	switch b {
 	case Major:
+		if v.IsPreRelease() && v.minor == 0 && v.patch == 0 {
+			return New(v.major, 0, 0)
+		}
 		return New(v.major+1, 0, 0)
 	case Minor:
+		if v.IsPreRelease() && v.patch == 0 {
+			return New(v.major, v.minor, 0)
+		}
 		return New(v.major, v.minor+1, 0)
+	case ReleaseCandidate:
+		if !v.IsPreRelease() {
+			return New(v.major, v.minor+1, 0).withPre(rcPrefix + "1")
+		}
+		if strings.HasPrefix(v.pre, rcPrefix) {
+			n, err := strconv.Atoi(v.pre[len(rcPrefix):])
+			if err == nil {
+				return v.withPre(rcPrefix + strconv.Itoa(n+1))
+			}
+		}
+		return v.withPre(rcPrefix + "1")
+	case Final:
+		return New(v.major, v.minor, v.patch)
 	default:
+		if v.IsPreRelease() {
+			return New(v.major, v.minor, v.patch)
+		}
 		return New(v.major, v.minor, v.patch+1)
 	}
 }
 
+func (v SemVer) Major() int {
+	return v.major
+}
+
+func (v SemVer) Minor() int {
+	return v.minor
+}
+
+func (v SemVer) Patch() int {
+	return v.patch
+}
+
+// PreRelease returns the pre-release identifiers, e.g. "rc.1",
+// or the empty string if this isn't a pre-release.
+func (v SemVer) PreRelease() string {
+	return v.pre
+}
+
+// Build returns the build metadata, e.g. "build.5", if any.
+func (v SemVer) Build() string {
+	return v.build
+}
+
+func (v SemVer) IsPreRelease() bool {
+	return v.pre != ""
+}
+
 func (v SemVer) BranchLabel() string {
 	return fmt.Sprintf("v%d.%d", v.major, v.minor)
 }
 
 func (v SemVer) String() string {
-	return fmt.Sprintf("v%d.%d.%d", v.major, v.minor, v.patch)
+	s := fmt.Sprintf("v%d.%d.%d", v.major, v.minor, v.patch)
+	if v.pre != "" {
+		s += "-" + v.pre
+	}
+	if v.build != "" {
+		s += "+" + v.build
+	}
+	return s
 }
 
 func (v SemVer) Pretty() string {
@@ -83,16 +240,96 @@ func (v SemVer) Pretty() string {
 	return v.String()
 }
 
+// Equals is true if the versions have the same precedence,
+// which means build metadata is ignored.
 func (v SemVer) Equals(o SemVer) bool {
-	return v.major == o.major && v.minor == o.minor && v.patch == o.patch
+	return v.compare(o) == 0
 }
 
 func (v SemVer) LessThan(o SemVer) bool {
-	return v.major < o.major ||
-		(v.major == o.major && v.minor < o.minor) ||
-		(v.major == o.major && v.minor == o.minor && v.patch < o.patch)
+	return v.compare(o) < 0
+}
+
+// compare returns -1, 0 or 1 as v has lower, equal or higher
+// precedence than o, per https://semver.org/#spec-item-11
+func (v SemVer) compare(o SemVer) int {
+	if c := compareInts(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareInts(v.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := compareInts(v.patch, o.patch); c != 0 {
+		return c
+	}
+	// A pre-release has lower precedence than the normal version.
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	}
+	vIds := strings.Split(v.pre, ".")
+	oIds := strings.Split(o.pre, ".")
+	for i := 0; i < len(vIds) && i < len(oIds); i++ {
+		if c := compareIdentifiers(vIds[i], oIds[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(vIds), len(oIds))
+}
+
+// compareIdentifiers compares numeric identifiers numerically,
+// and others lexically.  Numeric identifiers have lower precedence.
+func compareIdentifiers(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		if c := compareInts(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
 }
 
 func (v SemVer) IsZero() bool {
 	return v.Equals(zero)
 }
+
+// IsPseudo is true if the version is a pseudo-version, which
+// names an untagged commit rather than a release, e.g.
+// v0.0.0-20200101000000-abcdef123456.
+// See https://go.dev/ref/mod#pseudo-versions
+func (v SemVer) IsPseudo() bool {
+	return module.IsPseudoVersion(v.String())
+}
+
+// Revision returns the commit hash prefix, usually 12 hex
+// digits, of a pseudo-version, or "" if v isn't one.
+func (v SemVer) Revision() string {
+	if !v.IsPseudo() {
+		return ""
+	}
+	rev, err := module.PseudoVersionRev(v.String())
+	if err != nil {
+		return ""
+	}
+	return rev
+}
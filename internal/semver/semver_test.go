@@ -1,6 +1,7 @@
 package semver
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -35,6 +36,50 @@ func TestParse(t *testing.T) {
 			v:      zero,
 			errMsg: "\"v1.222\" doesn't have the form v1.2.3",
 		},
+		"too many fields": {
+			raw:    "v1.2.3.4",
+			v:      zero,
+			errMsg: "\"v1.2.3.4\" doesn't have the form v1.2.3",
+		},
+		"leading zero": {
+			raw:    "v1.02.3",
+			v:      zero,
+			errMsg: "\"v1.02.3\" has a malformed number",
+		},
+		"pre-release": {
+			raw:    "v0.10.0-rc.1",
+			v:      SemVer{major: 0, minor: 10, patch: 0, pre: "rc.1"},
+			errMsg: "",
+		},
+		"build": {
+			raw: "v1.0.0-alpha+exp.sha.5114f85",
+			v: SemVer{
+				major: 1, minor: 0, patch: 0,
+				pre: "alpha", build: "exp.sha.5114f85"},
+			errMsg: "",
+		},
+		"hyphen in pre-release": {
+			raw:    "v1.0.0-x-y-z.-",
+			v:      SemVer{major: 1, minor: 0, patch: 0, pre: "x-y-z.-"},
+			errMsg: "",
+		},
+		"empty pre-release identifier": {
+			raw:    "v1.0.0-rc..1",
+			v:      zero,
+			errMsg: "\"v1.0.0-rc..1\" has bad pre-release; empty identifier",
+		},
+		"pre-release leading zero": {
+			raw: "v1.0.0-rc.01",
+			v:   zero,
+			errMsg: "\"v1.0.0-rc.01\" has bad pre-release; " +
+				"numeric identifier \"01\" has a leading zero",
+		},
+		"bad build character": {
+			raw: "v1.0.0+a_b",
+			v:   zero,
+			errMsg: "\"v1.0.0+a_b\" has bad build metadata; " +
+				"identifier \"a_b\" has bad character '_'",
+		},
 	}
 	for n, tc := range testCases {
 		v, err := Parse(tc.raw)
@@ -43,10 +88,14 @@ func TestParse(t *testing.T) {
 				t.Errorf(
 					"%s: no error, but expected err %q", n, tc.errMsg)
 			}
-			if !v.Equals(tc.v) {
+			if v != tc.v {
 				t.Errorf(
 					"%s: expected %v, got %v", n, tc.v, v)
 			}
+			if v.String() != tc.raw {
+				t.Errorf(
+					"%s: expected string %q, got %q", n, tc.raw, v.String())
+			}
 		} else {
 			if tc.errMsg == "" {
 				t.Errorf(
@@ -93,6 +142,26 @@ func TestLessThan(t *testing.T) {
 			v2:       SemVer{major: 0, minor: 0, patch: 1},
 			expected: true,
 		},
+		"pre-release before release": {
+			v1:       SemVer{major: 1, minor: 3, patch: 0, pre: "rc.1"},
+			v2:       SemVer{major: 1, minor: 3, patch: 0},
+			expected: true,
+		},
+		"pre-release after lower release": {
+			v1:       SemVer{major: 1, minor: 3, patch: 0, pre: "rc.1"},
+			v2:       SemVer{major: 1, minor: 2, patch: 7},
+			expected: false,
+		},
+		"numeric identifiers compare numerically": {
+			v1:       SemVer{major: 1, minor: 0, patch: 0, pre: "rc.2"},
+			v2:       SemVer{major: 1, minor: 0, patch: 0, pre: "rc.10"},
+			expected: true,
+		},
+		"build ignored": {
+			v1:       SemVer{major: 1, minor: 0, patch: 0, build: "b"},
+			v2:       SemVer{major: 1, minor: 0, patch: 0, build: "a"},
+			expected: false,
+		},
 	}
 	for n, tc := range testCases {
 		actual := tc.v1.LessThan(tc.v2)
@@ -103,3 +172,234 @@ func TestLessThan(t *testing.T) {
 		}
 	}
 }
+
+func TestPrecedence(t *testing.T) {
+	// From https://semver.org/#spec-item-11
+	ordered := []string{
+		"v1.0.0-alpha",
+		"v1.0.0-alpha.1",
+		"v1.0.0-alpha.beta",
+		"v1.0.0-beta",
+		"v1.0.0-beta.2",
+		"v1.0.0-beta.11",
+		"v1.0.0-rc.1",
+		"v1.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		v1, _ := Parse(ordered[i])
+		v2, _ := Parse(ordered[i+1])
+		if !v1.LessThan(v2) || v2.LessThan(v1) {
+			t.Errorf("expected %s < %s", v1, v2)
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	var testCases = map[string]struct {
+		v        string
+		bump     SvBump
+		expected string
+	}{
+		"patch": {
+			v:        "v1.2.7",
+			bump:     Patch,
+			expected: "v1.2.8",
+		},
+		"minor": {
+			v:        "v1.2.7",
+			bump:     Minor,
+			expected: "v1.3.0",
+		},
+		"major": {
+			v:        "v1.2.7",
+			bump:     Major,
+			expected: "v2.0.0",
+		},
+		"first rc": {
+			v:        "v1.2.7",
+			bump:     ReleaseCandidate,
+			expected: "v1.3.0-rc.1",
+		},
+		"next rc": {
+			v:        "v1.3.0-rc.1",
+			bump:     ReleaseCandidate,
+			expected: "v1.3.0-rc.2",
+		},
+		"rc after beta": {
+			v:        "v1.3.0-beta.4",
+			bump:     ReleaseCandidate,
+			expected: "v1.3.0-rc.1",
+		},
+		"final": {
+			v:        "v1.3.0-rc.2",
+			bump:     Final,
+			expected: "v1.3.0",
+		},
+		"final of final": {
+			v:        "v1.3.0",
+			bump:     Final,
+			expected: "v1.3.0",
+		},
+		"minor finalizes rc": {
+			v:        "v1.3.0-rc.2",
+			bump:     Minor,
+			expected: "v1.3.0",
+		},
+		"major finalizes rc": {
+			v:        "v2.0.0-rc.2",
+			bump:     Major,
+			expected: "v2.0.0",
+		},
+		"major of minor rc": {
+			v:        "v1.3.0-rc.2",
+			bump:     Major,
+			expected: "v2.0.0",
+		},
+		"patch drops build": {
+			v:        "v1.3.0+meta",
+			bump:     Patch,
+			expected: "v1.3.1",
+		},
+	}
+	for n, tc := range testCases {
+		v, err := Parse(tc.v)
+		if err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+		actual := v.Bump(tc.bump).String()
+		if actual != tc.expected {
+			t.Errorf(
+				"%s: expected %s, got %s", n, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	var testCases = map[string]struct {
+		raw      string
+		expected SemVer
+		errMsg   string
+	}{
+		"ok": {
+			raw:      "v1.2",
+			expected: New(1, 2, 0),
+		},
+		"zero": {
+			raw:      "v0.0",
+			expected: New(0, 0, 0),
+		},
+		"full version": {
+			raw:    "v1.2.3",
+			errMsg: "doesn't have the form v1.2",
+		},
+		"pre-release": {
+			raw:    "v1.2-rc.1",
+			errMsg: "doesn't have the form v1.2",
+		},
+		"no v": {
+			raw:    "1.2",
+			errMsg: "doesn't have the form v1.2",
+		},
+	}
+	for n, tc := range testCases {
+		v, err := ParseLine(tc.raw)
+		if tc.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Errorf("%s: expected error containing %q, got %v",
+					n, tc.errMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", n, err)
+			continue
+		}
+		if !v.Equals(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", n, tc.expected, v)
+		}
+	}
+}
+
+func TestLatestOnLine(t *testing.T) {
+	versions := Versions{
+		New(1, 3, 0),
+		New(1, 2, 8),
+		New(1, 2, 7),
+		New(0, 9, 1),
+	}
+	var testCases = map[string]struct {
+		line     SemVer
+		expected SemVer
+		found    bool
+	}{
+		"latest": {
+			line:     New(1, 3, 0),
+			expected: New(1, 3, 0),
+			found:    true,
+		},
+		"older line": {
+			line:     New(1, 2, 0),
+			expected: New(1, 2, 8),
+			found:    true,
+		},
+		"missing line": {
+			line:  New(1, 1, 0),
+			found: false,
+		},
+	}
+	for n, tc := range testCases {
+		v, found := versions.LatestOnLine(tc.line)
+		if found != tc.found {
+			t.Errorf("%s: expected found %v, got %v", n, tc.found, found)
+			continue
+		}
+		if found && !v.Equals(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", n, tc.expected, v)
+		}
+	}
+}
+
+func TestPseudo(t *testing.T) {
+	var testCases = map[string]struct {
+		raw      string
+		revision string
+	}{
+		"release": {
+			raw: "v1.2.3",
+		},
+		"preRelease": {
+			raw: "v1.2.3-rc.1",
+		},
+		"noBase": {
+			raw:      "v0.0.0-20200101000000-abcdef123456",
+			revision: "abcdef123456",
+		},
+		"afterRelease": {
+			raw:      "v1.2.4-0.20200101000000-abcdef123456",
+			revision: "abcdef123456",
+		},
+		"afterPreRelease": {
+			raw:      "v1.2.3-rc.1.0.20200101000000-abcdef123456",
+			revision: "abcdef123456",
+		},
+		"majorSuffix": {
+			raw:      "v2.0.0-20200101000000-abcdef123456+incompatible",
+			revision: "abcdef123456",
+		},
+		"notATimestamp": {
+			raw: "v0.0.0-2020-abcdef123456",
+		},
+	}
+	for n, tc := range testCases {
+		v, err := Parse(tc.raw)
+		if err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+		if v.IsPseudo() != (tc.revision != "") {
+			t.Errorf("%s: expected IsPseudo %v", n, tc.revision != "")
+		}
+		if r := v.Revision(); r != tc.revision {
+			t.Errorf("%s: expected revision %q, got %q", n, tc.revision, r)
+		}
+	}
+}
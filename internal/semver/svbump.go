@@ -1,18 +1,46 @@
 package semver
 
+import "fmt"
+
 type SvBump int
 
 const (
 	Patch SvBump = iota
 	Minor
 	Major
+	// ReleaseCandidate starts or advances a series of release
+	// candidates, e.g. v1.2.7 -> v1.3.0-rc.1 -> v1.3.0-rc.2.
+	ReleaseCandidate
+	// Final drops the pre-release, e.g. v1.3.0-rc.2 -> v1.3.0.
+	Final
 )
 
 func (b SvBump) String() string {
 	return map[SvBump]string{
-		Patch: "Patch",
-		Minor: "Minor",
-		Major: "Major",
+		Patch:            "Patch",
+		Minor:            "Minor",
+		Major:            "Major",
+		ReleaseCandidate: "ReleaseCandidate",
+		Final:            "Final",
 	}[b]
 }
 
+// ParseBump converts a lowercase bump name, e.g. "minor", to a SvBump.
+func ParseBump(bump string) (SvBump, error) {
+	switch bump {
+	case "major":
+		return Major, nil
+	case "minor":
+		return Minor, nil
+	case "patch":
+		return Patch, nil
+	case "rc":
+		return ReleaseCandidate, nil
+	case "final":
+		return Final, nil
+	default:
+		return Patch, fmt.Errorf(
+			"unknown bump %s; specify one of "+
+				"'major', 'minor', 'patch', 'rc' or 'final'", bump)
+	}
+}
@@ -0,0 +1,62 @@
+// Package testutil holds fixtures shared by tests.
+package testutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/mod"
+	"github.com/monopole/gorepomod/internal/semver"
+	"golang.org/x/mod/modfile"
+)
+
+// RepoPath is the import path of FakeRepo.
+const RepoPath = "gh.com/micheal"
+
+// FakeRepo is a repository that exists in name only.
+type FakeRepo struct{}
+
+func (FakeRepo) RepoPath() string { return RepoPath }
+
+func (FakeRepo) AbsPath() string { return "/src/" + RepoPath }
+
+func (FakeRepo) FindModule(misc.ModuleShortName) misc.LaModule { return nil }
+
+// ImportPath is the import path of the named module in FakeRepo.
+func ImportPath(name misc.ModuleShortName) string {
+	if name == misc.ModuleAtTop {
+		return RepoPath
+	}
+	return RepoPath + "/" + string(name)
+}
+
+// NewModule makes a module in FakeRepo from the content of
+// its go.mod file, with v as its local and remote version.
+func NewModule(
+	t *testing.T, name misc.ModuleShortName,
+	v semver.SemVer, goMod string) *mod.Module {
+	mf, err := modfile.Parse("go.mod", []byte(goMod), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mod.New(FakeRepo{}, name, mf, v, v)
+}
+
+// NewModules makes untagged modules from a map of module name
+// to the names of the in-repo modules it requires at v1.0.0.
+func NewModules(
+	t *testing.T, names []string, deps map[string][]string) misc.LesModules {
+	var result misc.LesModules
+	for _, n := range names {
+		var b strings.Builder
+		b.WriteString("module " + ImportPath(misc.ModuleShortName(n)) + "\n")
+		for _, d := range deps[n] {
+			b.WriteString(
+				"require " + ImportPath(misc.ModuleShortName(d)) + " v1.0.0\n")
+		}
+		result = append(result, NewModule(
+			t, misc.ModuleShortName(n), semver.Zero(), b.String()))
+	}
+	return result
+}
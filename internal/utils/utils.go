@@ -13,6 +13,12 @@ func DirExists(name string) bool {
 	return info.IsDir()
 }
 
+// PathExists is true if the file or directory exists.
+func PathExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
 func SliceToSet(slice []string) map[string]bool {
 	result := make(map[string]bool)
 	for _, x := range slice {
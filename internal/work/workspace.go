@@ -0,0 +1,173 @@
+package work
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	// FileName is the name of a workspace file.
+	FileName = "go.work"
+	// The earliest Go version supporting workspaces.
+	goVersion = "1.18"
+)
+
+// Workspace is a go.work file in a repository's root directory,
+// listing (with "use" directives) the module directories to
+// build together.
+type Workspace struct {
+	// Absolute path to the go.work file.
+	path string
+	wf   *modfile.WorkFile
+}
+
+// Exists is true if the directory has a workspace file.
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, FileName))
+	return err == nil
+}
+
+// Load reads the workspace file in the given directory.
+func Load(dir string) (*Workspace, error) {
+	p := filepath.Join(dir, FileName)
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := modfile.ParseWork(p, content, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Workspace{path: p, wf: wf}, nil
+}
+
+// LoadOrNew reads the workspace file in the given directory,
+// or, if there isn't one, returns a new, empty workspace.
+func LoadOrNew(dir string) (*Workspace, error) {
+	if Exists(dir) {
+		return Load(dir)
+	}
+	wf := &modfile.WorkFile{Syntax: &modfile.FileSyntax{}}
+	if err := wf.AddGoStmt(goVersion); err != nil {
+		return nil, err
+	}
+	return &Workspace{path: filepath.Join(dir, FileName), wf: wf}, nil
+}
+
+// Path is the absolute path to the workspace file.
+func (w *Workspace) Path() string {
+	return w.path
+}
+
+// DirOf converts a module directory, relative to the
+// workspace directory, to the form used in "use" directives,
+// e.g. "kyaml" becomes "./kyaml".  Absolute paths are kept.
+func DirOf(rel string) string {
+	if filepath.IsAbs(rel) {
+		return filepath.Clean(rel)
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if rel == "." || strings.HasPrefix(rel, "../") {
+		return rel
+	}
+	return "./" + rel
+}
+
+// Uses returns the directories in "use" directives, sorted.
+func (w *Workspace) Uses() (result []string) {
+	for _, u := range w.wf.Use {
+		result = append(result, DirOf(u.Path))
+	}
+	sort.Strings(result)
+	return
+}
+
+// Includes is true if the workspace uses the directory.
+func (w *Workspace) Includes(dir string) bool {
+	for _, u := range w.wf.Use {
+		if DirOf(u.Path) == DirOf(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Use adds "use" directives for the directories not already used,
+// returning the directories added.
+func (w *Workspace) Use(dirs ...string) (added []string) {
+	for _, d := range dirs {
+		if w.Includes(d) {
+			continue
+		}
+		w.wf.AddNewUse(DirOf(d), "")
+		added = append(added, DirOf(d))
+	}
+	return
+}
+
+// Sync makes the workspace use exactly the given directories,
+// returning the directories added and dropped.
+func (w *Workspace) Sync(dirs []string) (added, dropped []string) {
+	want := make(map[string]bool)
+	for _, d := range dirs {
+		want[DirOf(d)] = true
+	}
+	for _, u := range w.Uses() {
+		if !want[u] {
+			dropped = append(dropped, u)
+		}
+	}
+	for _, u := range w.wf.Use {
+		if !want[DirOf(u.Path)] {
+			// Drop by the path as written.
+			_ = w.wf.DropUse(u.Path)
+		}
+	}
+	w.wf.Cleanup()
+	added = w.Use(dirs...)
+	return
+}
+
+// Check compares the workspace with the given module directories,
+// returning a description of each discrepancy.
+func (w *Workspace) Check(dirs []string) (problems []string) {
+	want := make(map[string]bool)
+	for _, d := range dirs {
+		want[DirOf(d)] = true
+		if !w.Includes(d) {
+			problems = append(problems,
+				fmt.Sprintf("module directory %s isn't used", DirOf(d)))
+		}
+	}
+	for _, u := range w.Uses() {
+		if !want[u] {
+			problems = append(problems,
+				fmt.Sprintf("used directory %s isn't a known module", u))
+		}
+	}
+	return
+}
+
+// Bytes returns the formatted workspace file content.
+func (w *Workspace) Bytes() []byte {
+	w.wf.SortBlocks()
+	w.wf.Cleanup()
+	return modfile.Format(w.wf.Syntax)
+}
+
+// Write writes the workspace file, or, if doIt is false,
+// merely prints what would be written.
+func (w *Workspace) Write(doIt bool) error {
+	content := w.Bytes()
+	if !doIt {
+		fmt.Printf("would write %s:\n%s", w.path, content)
+		return nil
+	}
+	return ioutil.WriteFile(w.path, content, 0644)
+}
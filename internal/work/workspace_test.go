@@ -0,0 +1,111 @@
+package work
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDirOf(t *testing.T) {
+	var testCases = map[string]struct {
+		rel      string
+		expected string
+	}{
+		"top":      {rel: ".", expected: "."},
+		"one":      {rel: "kyaml", expected: "./kyaml"},
+		"already":  {rel: "./kyaml", expected: "./kyaml"},
+		"nested":   {rel: "cmd/config/", expected: "./cmd/config"},
+		"upstairs": {rel: "../other", expected: "../other"},
+		"absolute": {rel: "/src/kyaml/", expected: "/src/kyaml"},
+	}
+	for n, tc := range testCases {
+		if actual := DirOf(tc.rel); actual != tc.expected {
+			t.Errorf("%s: expected %q, got %q", n, tc.expected, actual)
+		}
+	}
+}
+
+func TestSyncAndCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, FileName), []byte(`go 1.18
+
+use (
+	./api
+	./gone
+)
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := LoadOrNew(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := []string{"api", "kyaml", "cmd/config"}
+	problems := w.Check(modules)
+	expected := []string{
+		"module directory ./kyaml isn't used",
+		"module directory ./cmd/config isn't used",
+		"used directory ./gone isn't a known module",
+	}
+	if !reflect.DeepEqual(expected, problems) {
+		t.Errorf("expected problems %v, got %v", expected, problems)
+	}
+	added, dropped := w.Sync(modules)
+	if !reflect.DeepEqual([]string{"./kyaml", "./cmd/config"}, added) {
+		t.Errorf("unexpected added %v", added)
+	}
+	if !reflect.DeepEqual([]string{"./gone"}, dropped) {
+		t.Errorf("unexpected dropped %v", dropped)
+	}
+	if problems := w.Check(modules); len(problems) > 0 {
+		t.Errorf("unexpected problems after sync: %v", problems)
+	}
+	if err := w.Write(true); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedContent := `go 1.18
+
+use (
+	./api
+	./cmd/config
+	./kyaml
+)
+`
+	if string(content) != expectedContent {
+		t.Errorf("expected\n%s\ngot\n%s", expectedContent, content)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, err := LoadOrNew(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Use(".", "kyaml")
+	expected := `go 1.18
+
+use (
+	.
+	./kyaml
+)
+`
+	if string(w.Bytes()) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, w.Bytes())
+	}
+}
@@ -0,0 +1,115 @@
+// Package listing defines the document written by
+//
+//	gorepomod list --output=json
+//	gorepomod list --output=yaml
+//
+// so that other tools can consume it.
+package listing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Table = Format("table")
+	JSON  = Format("json")
+	YAML  = Format("yaml")
+)
+
+// ParseFormat converts a string to a Format.
+func ParseFormat(raw string) (Format, error) {
+	switch f := Format(raw); f {
+	case Table, JSON, YAML:
+		return f, nil
+	}
+	return Table, fmt.Errorf(
+		"unknown output %q; specify one of %q, %q or %q",
+		raw, Table, JSON, YAML)
+}
+
+// Repo describes a git repository holding Go modules.
+type Repo struct {
+	// RepoPath is the import path of the repository,
+	// e.g. sigs.k8s.io/kustomize
+	RepoPath string `json:"repoPath" yaml:"repoPath"`
+	// AbsPath is the repository's local filesystem path.
+	AbsPath string `json:"absPath" yaml:"absPath"`
+	// Remote is the git remote used for tags and branches.
+	Remote string `json:"remote" yaml:"remote"`
+	// MainBranch is the branch releases are made from, e.g. "main".
+	MainBranch string `json:"mainBranch" yaml:"mainBranch"`
+	// Workspace holds the short names of the modules used by
+	// the go.work file in the repository root, if there is one.
+	Workspace []string `json:"workspace,omitempty" yaml:"workspace,omitempty"`
+	Modules   []Module `json:"modules" yaml:"modules"`
+}
+
+// Module describes a Go module in the repository.
+type Module struct {
+	// ShortName is the module's path relative to the repository,
+	// as used in tags and in gorepomod commands, e.g. "kyaml".
+	ShortName string `json:"shortName" yaml:"shortName"`
+	// ImportPath is the module path, e.g. sigs.k8s.io/kustomize/kyaml
+	ImportPath string `json:"importPath" yaml:"importPath"`
+	// VersionLocal is the latest version tagged locally, if any.
+	VersionLocal string `json:"versionLocal,omitempty" yaml:"versionLocal,omitempty"`
+	// VersionRemote is the latest version tagged at the remote, if any.
+	VersionRemote string `json:"versionRemote,omitempty" yaml:"versionRemote,omitempty"`
+	// NeedsRelease is true if the module has changed since VersionLocal.
+	NeedsRelease bool `json:"needsRelease" yaml:"needsRelease"`
+	// UnreleasedCommits counts commits touching the module since VersionLocal.
+	UnreleasedCommits int `json:"unreleasedCommits" yaml:"unreleasedCommits"`
+	// Replacements are the module's replace directives, e.g.
+	// "sigs.k8s.io/kustomize/kyaml v0.10.0 => ../kyaml".
+	Replacements []string `json:"replacements,omitempty" yaml:"replacements,omitempty"`
+	// Dependencies are the in-repo modules this module requires.
+	Dependencies []Dependency `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	// Warnings describe problems to fix, e.g. tags whose
+	// major version disagrees with the module path, or
+	// dependencies on untagged commits.
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// Dependency is a requirement of one in-repo module on another.
+type Dependency struct {
+	ShortName  string `json:"shortName" yaml:"shortName"`
+	ImportPath string `json:"importPath" yaml:"importPath"`
+	// Version is the required version.
+	Version string `json:"version" yaml:"version"`
+	// Commit is the hash prefix of the commit required, if
+	// Version is a pseudo-version rather than a release.
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+}
+
+func (d Dependency) String() string {
+	if d.Version == "" {
+		return d.ShortName
+	}
+	return d.ShortName + "/" + d.Version
+}
+
+// Write writes the repo in the given format, which
+// must be JSON or YAML.  Tables are left to the caller.
+func (r *Repo) Write(w io.Writer, f Format) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case YAML:
+		out, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+	return fmt.Errorf("cannot write format %q", f)
+}
@@ -0,0 +1,92 @@
+package listing_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/monopole/gorepomod/listing"
+)
+
+func TestWrite(t *testing.T) {
+	r := &listing.Repo{
+		RepoPath:   "gh.com/micheal",
+		AbsPath:    "/home/micheal/gh.com/micheal",
+		Remote:     "origin",
+		MainBranch: "main",
+		Modules: []listing.Module{
+			{
+				ShortName:     "garage",
+				ImportPath:    "gh.com/micheal/garage",
+				VersionLocal:  "v1.2.3",
+				VersionRemote: "v1.2.3",
+				Dependencies: []listing.Dependency{
+					{
+						ShortName:  "car",
+						ImportPath: "gh.com/micheal/car",
+						Version:    "v0.1.0",
+					},
+				},
+			},
+		},
+	}
+	var testCases = map[string]struct {
+		format   listing.Format
+		expected string
+	}{
+		"yaml": {
+			format: listing.YAML,
+			expected: `repoPath: gh.com/micheal
+absPath: /home/micheal/gh.com/micheal
+remote: origin
+mainBranch: main
+modules:
+- shortName: garage
+  importPath: gh.com/micheal/garage
+  versionLocal: v1.2.3
+  versionRemote: v1.2.3
+  needsRelease: false
+  unreleasedCommits: 0
+  dependencies:
+  - shortName: car
+    importPath: gh.com/micheal/car
+    version: v0.1.0
+`,
+		},
+		"json": {
+			format: listing.JSON,
+			expected: `{
+  "repoPath": "gh.com/micheal",
+  "absPath": "/home/micheal/gh.com/micheal",
+  "remote": "origin",
+  "mainBranch": "main",
+  "modules": [
+    {
+      "shortName": "garage",
+      "importPath": "gh.com/micheal/garage",
+      "versionLocal": "v1.2.3",
+      "versionRemote": "v1.2.3",
+      "needsRelease": false,
+      "unreleasedCommits": 0,
+      "dependencies": [
+        {
+          "shortName": "car",
+          "importPath": "gh.com/micheal/car",
+          "version": "v0.1.0"
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+	for n, tc := range testCases {
+		var b bytes.Buffer
+		if err := r.Write(&b, tc.format); err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+		if b.String() != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", n, tc.expected, b.String())
+		}
+	}
+}
@@ -4,27 +4,55 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/monopole/gorepomod/internal/apidiff"
 	"github.com/monopole/gorepomod/internal/arguments"
+	"github.com/monopole/gorepomod/internal/config"
 	"github.com/monopole/gorepomod/internal/misc"
+	"github.com/monopole/gorepomod/internal/naming"
 	"github.com/monopole/gorepomod/internal/repo"
+	"github.com/monopole/gorepomod/internal/semver"
 )
 
 //go:generate go run internal/gen/main.go
 
-func loadRepoManager(args *arguments.Args) (*repo.Manager, error) {
-	path, err := os.Getwd()
+func loadRepoManager(
+	args *arguments.Args, wd string) (*repo.Manager, error) {
+	path, err := repo.FindRepoRoot(wd)
 	if err != nil {
 		return nil, err
 	}
-	dg, err := repo.NewDotGitDataFromPath(path)
+	cfg, err := config.Load(path)
 	if err != nil {
 		return nil, err
 	}
-	pr, err := dg.NewRepoFactory(args.Exclusions())
+	args.ApplyConfig(cfg)
+	if args.SkipPreflight() {
+		cfg.Preflight.Skip = true
+	}
+	if args.Changelog() {
+		cfg.Changelog = true
+	}
+	if args.Sign() && cfg.Signing.Format == "" && cfg.Signing.Key == "" {
+		cfg.Signing.Format = "gpg"
+	}
+	dg, err := repo.NewDotGitDataFromPath(path, args.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+	branch := cfg.BranchTemplate
+	if branch == "" && args.Strategy() == misc.BranchPerMajor {
+		branch = naming.DefaultBranchPerMajor
+	}
+	names, err := naming.New(branch, cfg.TagTemplate)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := dg.NewRepoFactory(
+		args.Exclusions(), args.Remote(), args.MainBranch(), names)
 	if err != nil {
 		return nil, err
 	}
-	return pr.NewRepoManager(), nil
+	return pr.NewRepoManager(cfg, args.Strategy()), nil
 }
 
 func actualMain() error {
@@ -33,14 +61,18 @@ func actualMain() error {
 		return err
 	}
 
-	mgr, err := loadRepoManager(args)
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	mgr, err := loadRepoManager(args, wd)
 	if err != nil {
 		return err
 	}
 
 	var targetModule misc.LaModule = nil
 	if args.ModuleName() != misc.ModuleUnknown {
-		targetModule = mgr.FindModule(args.ModuleName())
+		targetModule = mgr.FindModuleFrom(wd, args.ModuleName())
 		if targetModule == nil {
 			return fmt.Errorf(
 				"cannot find module %q in repo %s",
@@ -50,19 +82,77 @@ func actualMain() error {
 
 	switch args.GetCommand() {
 	case arguments.List:
-		return mgr.List()
+		return mgr.List(args.Output())
+	case arguments.Graph:
+		return mgr.Graph(args.GraphFormat())
+	case arguments.Changed:
+		return mgr.Changed()
 	case arguments.Tidy:
 		return mgr.Tidy(args.DoIt())
 	case arguments.Pin:
+		if args.NearestTag() {
+			return mgr.PinToNearestTags(args.DoIt(), targetModule)
+		}
 		v := args.Version()
 		if v.IsZero() {
 			v = targetModule.VersionLocal()
 		}
 		return mgr.Pin(args.DoIt(), targetModule, v)
 	case arguments.UnPin:
+		if args.Workspace() {
+			return mgr.UnPinWorkspace(args.DoIt(), targetModule)
+		}
 		return mgr.UnPin(args.DoIt(), targetModule)
+	case arguments.WorkInit:
+		return mgr.WorkInit(args.DoIt())
+	case arguments.WorkSync:
+		return mgr.WorkSync(args.DoIt())
+	case arguments.WorkCheck:
+		return mgr.WorkCheck()
 	case arguments.Release:
-		return mgr.Release(targetModule, args.Bump(), args.DoIt())
+		bump := args.Bump()
+		if !args.BumpSpecified() {
+			bump = mgr.DefaultBump(targetModule)
+		}
+		var api *apidiff.Report
+		if args.AutoBump() {
+			bump, api, err = mgr.SuggestBump(targetModule)
+			if err != nil {
+				return err
+			}
+		}
+		return mgr.Release(targetModule, bump, api, args.DoIt())
+	case arguments.SuggestBump:
+		_, _, err = mgr.SuggestBump(targetModule)
+		return err
+	case arguments.CheckDependents:
+		return mgr.CheckDependents(targetModule)
+	case arguments.ReleaseAll:
+		var targets misc.LesModules
+		for _, n := range args.ModuleNames() {
+			m := mgr.FindModuleFrom(wd, n)
+			if m == nil {
+				return fmt.Errorf(
+					"cannot find module %q in repo %s", n, mgr.RepoPath())
+			}
+			targets = append(targets, m)
+		}
+		bumpFor := mgr.DefaultBump
+		if args.BumpSpecified() {
+			bumpFor = func(misc.LaModule) semver.SvBump { return args.Bump() }
+		}
+		return mgr.ReleaseAll(targets, bumpFor, args.DoIt())
+	case arguments.Backport:
+		return mgr.Backport(
+			targetModule, args.Onto(), args.Commits(), args.DoIt())
+	case arguments.Resume:
+		return mgr.Resume(args.DoIt())
+	case arguments.Abort:
+		return mgr.Abort(args.DoIt())
+	case arguments.Verify:
+		return mgr.Verify(targetModule, args.Version())
+	case arguments.MigrateMajor:
+		return mgr.MigrateMajor(targetModule, args.DoIt())
 	case arguments.UnRelease:
 		return mgr.UnRelease(targetModule, args.DoIt())
 	case arguments.Debug:
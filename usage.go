@@ -12,7 +12,50 @@ It handles tasks one might otherwise attempt with
 find ./ -name "go.mod" | xargs {some hack}
 '''
 
-Run it from a git repository root.
+Run it from anywhere in a git repository (including
+a work tree made with 'git worktree add'); it finds the
+repository root by looking upward for '.git'.
+
+Commands taking a _{module}_ accept either the module's
+short name as shown by 'list' (e.g. 'kyaml'), or the path to
+the module's directory relative to the current directory,
+starting with '.' or '..', e.g. 'gorepomod release .'
+from inside the 'kyaml' directory.
+
+A module's short name is the path to its directory.  A module
+path's [major version suffix](https://go.dev/ref/mod#major-version-suffixes)
+may name a subdirectory holding that major version of the
+module, alongside the earlier versions (e.g. 'kyaml/v2'
+next to 'kyaml'), in which case the short name is
+'kyaml/v2'.  Both modules' tags start with 'kyaml/', as
+Go requires; _kyaml/v2.1.0_ belongs to 'kyaml/v2',
+and _kyaml/v1.3.0_ to 'kyaml'.
+
+Modules are found by searching the repository, skipping
+[excluded](#configuration) directories.  Other than below
+the top module, the search doesn't go below a module's
+directory, except into such major version subdirectories.
+
+The repository can be cloned anywhere; it needn't be
+under '$GOPATH/src'.  The repository's import path (e.g.
+'sigs.k8s.io/kustomize') is derived from the module paths in
+its 'go.mod' files, or failing that, from the URL of its git remote.
+Use the '--repoPath' flag to specify it explicitly, e.g.
+
+'''
+gorepomod list --repoPath=sigs.k8s.io/kustomize
+'''
+
+Tags and release branches are fetched from and pushed to a git
+remote named 'upstream' or 'origin' (or the only remote, if
+there's just one), and releases are made from the remote's
+default branch (per 'refs/remotes/{remote}/HEAD', else
+'main' or 'master').  Use the '--remote' and '--mainBranch'
+flags to choose otherwise, e.g.
+
+'''
+gorepomod release kyaml --remote=github --mainBranch=main
+'''
 
 It walks the repository, reads 'go.mod' files, builds
 a model of Go modules and intra-repo module
@@ -26,22 +69,68 @@ go get github.com/monopole/gorepomod
 ## Usage
 
 _Commands that change things (everything but 'list')
-do nothing but log commands
+do nothing but log commands (and show 'go.mod'
+changes as unified diffs)
 unless you add the '--doIt' flag,
 allowing the change._
 
-#### 'gorepomod list'
+#### 'gorepomod list [--output=table|json|yaml]'
 
 Lists modules and intra-repo dependencies.
 
 Use this to get module names for use in other commands.
 
+The default output is a table.  With '--output=json' or
+'--output=yaml', the command writes a document holding,
+for each module, its short name, import path, local and
+remote versions, replacements and intra-repo dependencies
+(with the required versions).  The schema is defined by
+the Go types in the package
+'github.com/monopole/gorepomod/listing'.
+
+The 'UNRELEASED' column holds the number of commits touching
+a module since its most recent local tag, if the module
+has changed since that tag (see 'changed').
+
+The command warns about a module whose latest tag disagrees
+with its module path's [major version suffix](https://go.dev/ref/mod#major-version-suffixes),
+e.g. a _v2.0.0_ tag for a module path without '/v2',
+which the Go toolchain sees as _v2.0.0+incompatible_.
+It also warns about a module requiring an in-repo module at a
+[pseudo-version](https://go.dev/ref/mod#pseudo-versions)
+(e.g. _v0.0.0-20200101000000-abcdef123456_), i.e. at an untagged
+commit rather than a release, naming the earliest release having
+that commit (see 'pin --nearestTag').
+
+#### 'gorepomod graph [dot|mermaid]'
+
+Writes the intra-repo module dependency graph
+in [DOT](https://graphviz.org/doc/info/lang.html) (the default)
+or [Mermaid](https://mermaid-js.github.io) form.
+
+Each module is a node labelled with its latest local version.
+An edge from module _m_ to module _n_ means _m_ requires _n_,
+and is labelled with the required version.
+If _m_ replaces _n_ with a local path (i.e. _n_ is unpinned),
+the edge is dashed.
+
+E.g. 'gorepomod graph | dot -Tsvg > deps.svg'
+
+#### 'gorepomod changed'
+
+For each module, reports whether the module's files differ
+from those at the module's most recent local tag (i.e. whether
+it needs a release), and how many commits touched them.
+
+Directories holding nested modules are not considered
+part of the enclosing module.
+
 #### 'gorepomod tidy'
 
 Creates a change with mechanical updates
 to 'go.mod' and 'go.sum' files.
 
-#### 'gorepomod unpin {module}'
+#### 'gorepomod unpin {module} [--workspace]'
 
 Creates a change to 'go.mod' files.
 
@@ -50,7 +139,27 @@ if _m_ depends on a _{module}_,
 then _m_'s dependency on it will be replaced by
 a relative path to the in-repo module.
 
-#### 'gorepomod pin {module} [{version}]'
+With '--workspace', no 'go.mod' file is changed.
+Instead, _{module}_ and each module _m_ depending on it
+are added to the 'go.work' file in the repository root
+(creating it if need be), so that builds in the workspace
+use the local code of _{module}_.
+The 'go.work' file isn't meant to be committed; when done,
+delete it, or set 'GOWORK=off'.
+
+#### 'gorepomod work init|sync|check'
+
+Manages a ['go.work'](https://go.dev/ref/mod#workspaces) file in the
+repository root covering all modules found in the repository.
+
+ - 'init' creates the file, failing if it already exists.
+ - 'sync' creates or updates the file, adding modules it
+   doesn't use, and dropping directories that aren't modules.
+ - 'check' reports discrepancies between the file and the modules.
+
+If a 'go.work' file exists, 'list' reports the modules it uses.
+
+#### 'gorepomod pin {module} [{version}] [--nearestTag]'
 
 Creates a change to 'go.mod' files.
 
@@ -63,33 +172,241 @@ The argument _{version}_ defaults to recent version of _{module}_.
 
 _{version}_ should be in semver form, e.g. 'v1.2.3'.
 
+With '--nearestTag', only modules requiring _{module}_ at a
+[pseudo-version](https://go.dev/ref/mod#pseudo-versions), i.e.
+at an untagged commit, are changed, each pinned to the earliest
+version of _{module}_ whose tag contains that commit (see 'list').
+
+
+#### 'gorepomod suggest-bump {module}'
+
+Compares the exported Go API of the module at its most recent
+local tag with the API in the working tree, lists the
+changes, and recommends a bump, either 'patch', 'minor' or 'major'.
+
+ - Removed or modified API calls for 'major'
+   (or 'minor' if the major version is zero).
+ - Added API calls for 'minor' (or 'patch' if the
+   major version is zero).
+ - Otherwise, 'patch'.
+
+Packages below 'internal', 'testdata' and 'vendor'
+directories, tests and commands are ignored.
+
+#### 'gorepomod check-dependents {module}'
+
+Checks whether the in-repo modules depending on the
+module still work with its local, unreleased code.
+
+For each such module, the command writes a temporary
+'go.work' file using the dependent and the module, and
+runs the [preflight](#configuration) commands (by default
+'go build', 'go vet' and 'go test') in the dependent.
+It prints a matrix of the results, with a row per
+dependent, followed by the output of any failed command.
+No 'go.mod' file is changed.
 
-#### 'gorepomod release {module} [patch|minor|major]'
+#### 'gorepomod release {module} [patch|minor|major|rc|final|auto] [--skipPreflight] [--sign] [--changelog]'
 
 Computes a new version for the module, tags the repo
 with that version, and pushes the tag to the remote.
 
-The value of the 2nd argument, either 'patch' (the default),
+The value of the 2nd argument, either 'patch' (the default,
+unless [configured](#configuration) otherwise),
 'minor' or 'major', determines the new version.
+The value 'auto' uses the bump recommended by 'suggest-bump'.
+
+A release is refused if the new version's major version
+disagrees with the module path's major version suffix, e.g.
+'major' from _v1.4.0_ for a module path without '/v2'.
+
+A 'patch' release is refused if the module's exported
+API has incompatible changes since its most recent tag.
+If the API can't be compared (e.g. a file doesn't parse),
+a warning is printed and the release goes ahead.
 
 If the existing version is _v1.2.7_, then the new version will be:
  - 'patch' -> _v1.2.8_
  - 'minor' -> _v1.3.0_
  - 'major' -> _v2.0.0_
+ - 'rc' -> _v1.3.0-rc.1_
+
+Versions follow [semver 2.0](https://semver.org), including
+pre-release and build metadata, e.g. _v1.3.0-rc.1+build.5_.
+
+If the existing version is a release candidate like _v1.3.0-rc.1_:
+ - 'rc' -> _v1.3.0-rc.2_
+ - 'final' -> _v1.3.0_
+ - 'patch', 'minor' -> _v1.3.0_, i.e. the version the candidate precedes
+ - 'major' -> _v2.0.0_
+
+Otherwise, 'final' is refused.
+
+Before pushing or tagging anything, the command runs preflight
+checks against the code being released, i.e. the release branch
+after merging the main branch into it, or with the 'trunk'
+strategy, the remote main branch checked out in a temporary work
+tree.  The checks are 'go build ./...', 'go vet ./...' and
+'go test ./...' in the module, with any 'go.work' file ignored,
+so the module builds against exactly the dependencies its
+'go.mod' names.  The commands can be [configured](#configuration),
+as can running them in each in-repo module depending on the
+module, built against the module's code being released.
+The command prints a summary per module, and stops if any check fails.
+Use '--skipPreflight' to skip the checks.
 
 After establishing the the version, the command looks for a branch named
 
 > _release-{module}/-v{major}.{minor}_
 
 If the branch doesn't exist, the command creates it and pushes it to the remote.
+Release branches are never force pushed; if the local branch and
+the remote's branch have diverged, the command stops and says how.
 
 The command then creates a new tag in the form
 
 > _{module}/v{major}.{minor}.{patch}_
 
+The '--strategy' flag (or the 'strategy' [setting](#configuration))
+selects which of these steps run:
+
+ - 'branch-per-minor' (the default) - as described above.
+ - 'branch-per-major' - as above, but release branches are per
+   major version, e.g. _release-{module}-v{major}_.
+ - 'trunk' - no release branches; the command fetches the
+   remote and tags the remote's main branch directly, leaving
+   local branches and the working tree alone.
+
+Branch and tag names can be [configured](#configuration)
+with templates.  Tags must remain in the form the Go
+toolchain requires, which is checked.
+
+The tag's message holds release notes, made from the commits
+touching the module's files (but not those of nested modules)
+since the module's previous tag, omitting merges.  Commits are
+grouped by their [conventional commit](https://www.conventionalcommits.org)
+type ('feat', 'fix', 'perf', 'docs', and '!' or a 'BREAKING CHANGE:'
+footer for breaking changes), or failing that, by pull request
+labels named in the commit message on a line like
+'Labels: kind/bug, area/kyaml'.
+
+With '--changelog' (or the 'changelog' [setting](#configuration)),
+the release notes are also added to a 'CHANGELOG.md' file in the
+module's directory, committed to the main branch, which is merged
+into the release branch and pushed along with it.  This isn't
+possible with the 'trunk' strategy.
+
+Tags are annotated.  To sign them, use '--sign' (signing
+with GPG and git's 'user.signingKey'), or [configure](#configuration)
+the signature format ('gpg', 'ssh' or 'x509') and key.  A key
+configured without a format is used in the format set by git's
+'gpg.format' setting.
+
+Signatures are checked by 'verify', going by the exit status of
+'git tag -v'.
+
 The command pushes this tag to the remote.  This typically triggers
 cloud activity to create release artifacts.
 
+The command records each step it completes in a journal
+('.git/gorepomod-journal.json').  If a step fails before
+anything was pushed, the command undoes its local effects,
+deleting the local tag and returning to the original branch
+and commit.  If something was already pushed, the journal
+is kept, and no other release can start until you run one of
+
+#### 'gorepomod resume'
+
+Continues the release in progress from the step that failed,
+e.g. after fixing a network or permissions problem.
+
+#### 'gorepomod abort'
+
+Undoes the local effects of the release in progress
+(deleting the local tag, and returning to the original
+branch and commit) and deletes the journal.  Anything already
+pushed remains, and is reported; see 'unrelease'.
+
+#### 'gorepomod verify {module} [{version}]'
+
+Checks the signature of the module's local tag for the
+version, or if no version is given, of every local tag
+of the module, failing if any is unsigned or badly signed.
+Verifying SSH signatures requires git's
+'gpg.ssh.allowedSignersFile' setting.
+
+#### 'gorepomod release-all [patch|minor|major] [{module} ...] [--skipPreflight] [--sign] [--changelog]'
+
+Releases several modules in dependency order.
+
+If no modules are named, every module with changes since
+its most recent local tag is released, except those
+configured to never be released.
+
+The command orders the modules using intra-repo dependencies,
+so that a module is released only after the in-repo
+modules it depends on.  It fails if the dependencies form a cycle.
+
+For each module in that order, the command performs a
+'release' (all modules get the same bump if one is given,
+otherwise each gets its [configured](#configuration) default),
+then pins every module that depends on it to the new version,
+commits that change, and pushes the commit to the remote
+main branch, so that the next release includes it.
+
+#### 'gorepomod backport {module} {commit...} --onto={line} [--sign]'
+
+Makes a patch release on an older release line, e.g.
+_v1.2.8_ after the main branch has moved on to _v1.3_ work:
+
+'''
+gorepomod backport kyaml 1a2b3c 4d5e6f --onto=v1.2
+'''
+
+The command checks out the line's release branch,
+e.g. _release-kyaml-v1.2_ (creating it at the line's most
+recent tag if it exists neither locally nor at the remote),
+cherry-picks the commits onto it, runs the preflight checks
+(see 'release'), pushes it, then tags the next patch version
+on the line and pushes the tag.
+The main branch is not merged into the release branch.
+
+The command needs a release branch per minor version, so it
+refuses to run with the 'branch-per-major' strategy, whose
+branches may hold newer work than the line, or with 'trunk'.
+
+If a commit doesn't apply cleanly, the cherry-pick is aborted,
+the originally checked out branch is restored, and the
+conflicting files are reported.  Like 'release', the command
+keeps a journal, undoing its local effects if a step fails
+before anything was pushed; otherwise see 'resume' and 'abort'.
+
+#### 'gorepomod migrate-major {module}'
+
+Prepares the module for its next major version, which
+[needs a new module path](https://go.dev/ref/mod#major-version-suffixes),
+e.g. moving 'sigs.k8s.io/kustomize/kyaml' to
+'sigs.k8s.io/kustomize/kyaml/v2' for _v2.0.0_.
+
+The command
+ - rewrites the imports of the module's packages in the
+   Go files of every in-repo module (leaving alone nested
+   modules, 'vendor' and 'testdata' directories),
+ - changes the 'module' directive in the module's 'go.mod',
+ - has each in-repo module requiring the module require the
+   new path at the new version instead, replacing it with the
+   module's directory (or keeping an existing replacement),
+   as the new version isn't released yet.
+
+The module must already be released at its current major
+version, e.g. _v1_ to move to _v2_.  The workspace must be
+clean, so that the change can be reviewed (or undone) before
+committing it.  Then release the module ('release {module} major'),
+and 'pin' it.
+
+This keeps the module in its directory; to keep developing
+the previous major version, branch from its last release.
+
 #### 'gorepomod unrelease {module}'
 
 This undoes the work of 'release', by deleting the
@@ -105,5 +422,69 @@ because it will confuse module caches.
 
 Do a new patch release instead.
 
+## Configuration
+
+An optional '.gorepomod.yaml' file in the repository root
+holds settings shared by everyone working on the repository.
+Command line flags override them.
+
+'''
+# Directories not searched for modules, besides those in the
+# built-in list (.git, docs, examples, hack, site, etc.).
+# A pattern without a slash matches a directory's name anywhere,
+# a pattern with a slash matches its path from the repository root.
+excludes:
+- testdata
+- "plugin/*/internal"
+
+# The git remote and main branch to use (see --remote, --mainBranch).
+remote: upstream
+mainBranch: main
+
+# The release strategy (see --strategy).
+strategy: trunk
+
+# Go text/templates naming release branches and tags, over the
+# fields .Module (empty for the top module, and without any
+# major version subdirectory, e.g. kyaml for kyaml/v2), .ImportPath,
+# .Version, .Major, .Minor, .Patch and .Pre (e.g. rc.1).
+# These are the defaults:
+branchTemplate: "release-{{if .Module}}{{.Module}}-{{end}}v{{.Major}}.{{.Minor}}"
+tagTemplate: "{{if .Module}}{{.Module}}/{{end}}{{.Version}}"
+
+# The bump used by release and release-all if none is given.
+defaultBump: minor
+
+# Checks run before a release (see release).  Commands aren't run
+# by a shell, but are split into arguments like one would, so
+# arguments can be quoted, e.g. go test -run "TestA|TestB" ./...
+# These are the default commands:
+preflight:
+  commands:
+  - go build ./...
+  - go vet ./...
+  - go test ./...
+  # Also run them in each in-repo dependent of the module.
+  dependents: true
+  # Or turn the checks off.
+  skip: false
+
+# How release tags are signed (see --sign); by default they
+# aren't.  The format is gpg, ssh or x509, and the key defaults
+# to git's user.signingKey.  For ssh, the key is a file path.
+signing:
+  format: ssh
+  key: /home/me/.ssh/id_ed25519
+
+# Add release notes to each module's CHANGELOG.md (see --changelog).
+changelog: true
+
+# Per-module settings, keyed by the names shown by 'list'.
+modules:
+  kyaml:
+    defaultBump: patch
+  hack/tools:
+    neverRelease: true
+'''
 `
 )